@@ -8,21 +8,31 @@ import (
 	"context"
 	"os"
 	"os/signal"
+	"time"
 
 	"github.com/spf13/cobra"
 	config "github.com/tupyy/device-worker-ng/configuration"
+	"github.com/tupyy/device-worker-ng/internal/admin"
 	"github.com/tupyy/device-worker-ng/internal/certificate"
 	httpClient "github.com/tupyy/device-worker-ng/internal/client/http"
 	"github.com/tupyy/device-worker-ng/internal/configuration"
 	"github.com/tupyy/device-worker-ng/internal/edge"
+	"github.com/tupyy/device-worker-ng/internal/entities"
 	"github.com/tupyy/device-worker-ng/internal/executor"
+	"github.com/tupyy/device-worker-ng/internal/logbroker"
+	"github.com/tupyy/device-worker-ng/internal/metrics"
+	"github.com/tupyy/device-worker-ng/internal/notifier"
 	"github.com/tupyy/device-worker-ng/internal/profile"
 	"github.com/tupyy/device-worker-ng/internal/resources"
 	"github.com/tupyy/device-worker-ng/internal/scheduler"
+	"github.com/tupyy/device-worker-ng/internal/tlsconfig"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
+const clusterTLSProfileName = "cluster"
+const adminTLSProfileName = "server"
+
 var (
 	configFile            string
 	caRoot                string
@@ -32,6 +42,11 @@ var (
 	namespace             string
 	logLevel              string
 	profileManagerEnabled bool
+	retrySleep            time.Duration
+	retryTimeout          time.Duration
+	notifierOutboxPath    string
+	adminListen           string
+	adminTokenJarPath     string
 )
 
 const (
@@ -50,53 +65,132 @@ var rootCmd = &cobra.Command{
 
 		config.InitConfiguration(cmd, configFile)
 
-		certManager, err := initCertificateManager(caRoot, certFile, privateKey)
+		clusterProfile, err := tlsconfig.New(config.GetTLSProfile(clusterTLSProfileName))
 		if err != nil {
 			panic(err)
 		}
+		certManager := clusterProfile.CertManager()
+
+		metricsRegistry := metrics.New()
+		// this process reports every sample it takes; fleets that need to
+		// downsample per-device metrics can lower this once that knob exists.
+		metricsRegistry.SetSampleRate(1)
 
 		// httpClient is a wrapper around http client which implements yggdrasil API.
-		httpClient, err := httpClient.New(config.GetServerAddress(), certManager)
+		httpClient, err := httpClient.New(config.GetServerAddress(), clusterProfile, retryPolicies(), metricsRegistry)
 		if err != nil {
 			panic(err)
 		}
 
+		// this should be the last step, in order to avoid data races.
+		// starting in right order the controller, scheduler and profile manager
+		ctx, cancel := context.WithCancel(context.Background())
+
+		go clusterProfile.Watch(ctx, 0)
+
+		// rotated fires every time the certificate is renewed so other subsystems can
+		// rebuild anything keyed off the certificate signature. forceRotate backs the
+		// admin API's POST /certificates/rotate endpoint.
+		rotated, forceRotate, cancelRenewal := certManager.StartAutoRenewal(ctx, httpClient, httpClient, certificate.AutoRenewalConfig{
+			DeviceID: config.GetDeviceID(),
+			CertPath: certFile,
+			KeyPath:  privateKey,
+		})
+		go logCertificateRotations(rotated)
+		go reportCertificateExpiry(ctx, certManager, metricsRegistry)
+
 		confManager := configuration.New(profileManagerEnabled)
-		executor, err := executor.New()
+		// logBroker fans out each workload's stdout/stderr to the subscribers
+		// that follow it; the edge controller subscribes httpClient to a
+		// workload's logs when a configuration update lists it in LogTargets.
+		logBroker := logbroker.New()
+		executor, err := executor.New(logBroker, metricsRegistry)
 		if err != nil {
 			panic(err)
 		}
 
-		controller := edge.New(httpClient, confManager, certManager)
+		controller := edge.New(httpClient, confManager, logBroker, config.GetDeviceID())
 		var profileManager *profile.Manager
+		var notifyManager *notifier.Manager
+		var schedulerProfileCh chan map[string]entities.ProfileState
 		if profileManagerEnabled {
 			profileManager = profile.New(confManager.StateManagerCh)
+
+			// profileManager.OutputCh only has one reader, so fan it out to the
+			// scheduler (which acts on the new state) and the notifier (which
+			// alerts an operator about it).
+			schedulerProfileCh = make(chan map[string]entities.ProfileState)
+			notifierProfileCh := make(chan map[string]entities.ProfileState)
+			go fanOutProfileUpdates(ctx, profileManager.OutputCh, metricsRegistry, schedulerProfileCh, notifierProfileCh)
+
+			outbox, err := notifier.OpenOutbox(notifierOutboxPath)
+			if err != nil {
+				panic(err)
+			}
+			// Routes start empty and are populated once the edge controller's
+			// first poll of the operator's configuration comes back, rather
+			// than from static CLI/config-file settings, so an operator can
+			// change notification routing without a worker restart.
+			notifyManager = notifier.New(config.GetDeviceID(), nil, config.GetNotifierHoldDown(), outbox)
+			controller.SetNotifier(notifyManager)
+			notifyManager.Start(ctx, notifierProfileCh)
 		}
 		resourceManager := resources.New()
 		// setup scheduler
-		scheduler := scheduler.New(executor, resourceManager)
+		scheduler := scheduler.New(executor, resourceManager, metricsRegistry)
 		//	confManager.SetWorkloadStatusReader(scheduler)
 
-		// this should be the last step, in order to avoid data races.
-		// starting in right order the controller, scheduler and profile manager
-		ctx, cancel := context.WithCancel(context.Background())
 		controller.Start(ctx)
 		if profileManagerEnabled {
-			scheduler.Start(ctx, confManager.SchedulerCh, profileManager.OutputCh)
+			scheduler.Start(ctx, confManager.SchedulerCh, schedulerProfileCh)
 			profileManager.Start(ctx)
 		} else {
 			scheduler.Start(ctx, confManager.SchedulerCh, nil)
 		}
 
+		var adminServer *admin.Server
+		if adminListen != "" {
+			adminProfile, err := tlsconfig.New(config.GetTLSProfile(adminTLSProfileName))
+			if err != nil {
+				panic(err)
+			}
+			go adminProfile.Watch(ctx, 0)
+
+			jar, err := admin.OpenJar(adminTokenJarPath)
+			if err != nil {
+				panic(err)
+			}
+
+			adminCfg := admin.Config{
+				Listen:      adminListen,
+				Profile:     adminProfile,
+				Jar:         jar,
+				Workloads:   schedulerWorkloadsAdapter{scheduler},
+				RotateCerts: func() { forceRotate() },
+				Metrics:     metricsRegistry.Handler(),
+			}
+			if profileManagerEnabled {
+				adminCfg.Profiles = profileManagerAdapter{profileManager}
+			}
+
+			adminServer = admin.New(adminCfg)
+			adminServer.Start(ctx)
+		}
+
 		done := make(chan os.Signal, 1)
 		signal.Notify(done, os.Interrupt, os.Kill)
 
 		<-done
 
 		cancel()
+		cancelRenewal()
 		controller.Shutdown(ctx)
 		if profileManagerEnabled {
 			profileManager.Shutdown(ctx)
+			notifyManager.Shutdown()
+		}
+		if adminServer != nil {
+			adminServer.Shutdown(ctx)
 		}
 	},
 }
@@ -117,6 +211,34 @@ func init() {
 	rootCmd.Flags().StringVar(&namespace, "namespace", "default", "target namespace")
 	rootCmd.Flags().StringVar(&logLevel, "log-level", "info", "log level")
 	rootCmd.Flags().BoolVar(&profileManagerEnabled, "enable-profile-manager", true, "enable profile manager")
+	rootCmd.Flags().DurationVar(&retrySleep, "retry-sleep", time.Second, "initial delay between retries of a failed request")
+	rootCmd.Flags().DurationVar(&retryTimeout, "retry-timeout", 30*time.Second, "overall wall-clock budget allowed to retry a failed request")
+	rootCmd.Flags().StringVar(&notifierOutboxPath, "notifier-outbox", "/var/lib/device-worker-ng/notifier.outbox", "path to the on-disk queue of undelivered profile notifications")
+	rootCmd.Flags().StringVar(&adminListen, "admin-listen", "", "address for the local admin HTTP API to listen on (disabled if empty)")
+	rootCmd.Flags().StringVar(&adminTokenJarPath, "admin-token-jar", "/var/lib/device-worker-ng/tokens.json", "path to the admin API's token jar")
+}
+
+// retryPolicies builds the per-method retry policies used by the http client.
+// Every method defaults to the sleep/timeout CLI flags; a method-specific
+// retry-timeout in the configuration file overrides the default for that
+// method alone, so operators can tune e.g. heartbeat separately from enrol.
+func retryPolicies() httpClient.RetryPolicies {
+	policyFor := func(method string) httpClient.RetryPolicy {
+		policy := httpClient.RetryPolicy{Sleep: retrySleep, RetryTimeout: retryTimeout, MaxSleep: retryTimeout}
+		if override, ok := config.GetRetryTimeout(method); ok {
+			policy.RetryTimeout = override
+			policy.MaxSleep = override
+		}
+		return policy
+	}
+
+	return httpClient.RetryPolicies{
+		Enrol:         policyFor("enrol"),
+		Register:      policyFor("register"),
+		Heartbeat:     policyFor("heartbeat"),
+		Configuration: policyFor("configuration"),
+		Logs:          policyFor("logs"),
+	}
 }
 
 func setupLogger() *zap.Logger {
@@ -151,27 +273,90 @@ func setupLogger() *zap.Logger {
 	return plain
 }
 
-func initCertificateManager(caroot, certFile, keyFile string) (*certificate.Manager, error) {
-	// read certificates
-	caRoot, err := os.ReadFile(caroot)
-	if err != nil {
-		return nil, err
+// fanOutProfileUpdates forwards every value read from in to both out channels
+// until ctx is cancelled, since a chan's values can only be consumed once. It
+// also times the gap between consecutive evaluations and reports it as the
+// profile evaluation latency, since the evaluator itself lives outside this
+// package.
+func fanOutProfileUpdates(ctx context.Context, in <-chan map[string]entities.ProfileState, registry *metrics.Registry, outs ...chan<- map[string]entities.ProfileState) {
+	var last time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case states, ok := <-in:
+			if !ok {
+				return
+			}
+
+			if !last.IsZero() {
+				registry.ObserveProfileEvaluation(time.Since(last))
+			}
+			last = time.Now()
+
+			for _, out := range outs {
+				out <- states
+			}
+		}
 	}
+}
 
-	cert, err := os.ReadFile(certFile)
-	if err != nil {
-		return nil, err
+// logCertificateRotations drains the rotation channel until it is closed,
+// logging every certificate renewal. rotated has exactly one reader
+// (StartAutoRenewal's channel is not fanned out), so this is currently the
+// only place that reacts to a rotation.
+func logCertificateRotations(rotated <-chan certificate.RotationEvent) {
+	for event := range rotated {
+		zap.S().Infow("certificate rotated", "renewedAt", event.RenewedAt)
 	}
+}
 
-	privateKey, err := os.ReadFile(keyFile)
-	if err != nil {
-		return nil, err
+// reportCertificateExpiry polls certManager's current leaf certificate every
+// minute and records how many seconds remain until it expires.
+func reportCertificateExpiry(ctx context.Context, certManager *certificate.Manager, registry *metrics.Registry) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, leaf, _ := certManager.GetCertificates()
+			if leaf == nil {
+				continue
+			}
+			registry.SetCertificateExpiry(time.Until(leaf.NotAfter))
+		}
 	}
+}
 
-	certManager, err := certificate.New([][]byte{caRoot}, cert, privateKey)
-	if err != nil {
-		return nil, err
+// schedulerWorkloadsAdapter adapts *scheduler.Scheduler to admin.WorkloadsReader
+// so the admin package does not need to depend on the scheduler package.
+type schedulerWorkloadsAdapter struct {
+	scheduler *scheduler.Scheduler
+}
+
+func (a schedulerWorkloadsAdapter) Workloads() []admin.WorkloadStatus {
+	workloads := a.scheduler.Workloads()
+	statuses := make([]admin.WorkloadStatus, 0, len(workloads))
+	for _, w := range workloads {
+		statuses = append(statuses, admin.WorkloadStatus{ID: w.ID, State: w.State})
 	}
+	return statuses
+}
 
-	return certManager, nil
+func (a schedulerWorkloadsAdapter) RestartWorkload(id string) error {
+	return a.scheduler.RestartWorkload(id)
 }
+
+// profileManagerAdapter adapts *profile.Manager to admin.ProfilesReader.
+type profileManagerAdapter struct {
+	profileManager *profile.Manager
+}
+
+func (a profileManagerAdapter) Profiles() map[string]string {
+	return a.profileManager.States()
+}
+
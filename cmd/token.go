@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tupyy/device-worker-ng/internal/admin"
+)
+
+var (
+	tokenJarPath string
+	tokenLabel   string
+	tokenScopes  []string
+	tokenTTL     time.Duration
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage the admin API's bearer tokens",
+}
+
+var tokenAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Mint a new bearer token and add it to the jar",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		jar, err := admin.OpenJar(tokenJarPath)
+		if err != nil {
+			return err
+		}
+
+		scopes := make([]admin.Scope, 0, len(tokenScopes))
+		for _, s := range tokenScopes {
+			scopes = append(scopes, admin.Scope(s))
+		}
+
+		token, err := jar.Add(tokenLabel, scopes, tokenTTL)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(token.Value)
+		return nil
+	},
+}
+
+var tokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the tokens in the jar",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		jar, err := admin.OpenJar(tokenJarPath)
+		if err != nil {
+			return err
+		}
+
+		for _, t := range jar.List() {
+			fmt.Printf("%s\t%s\t%s\t%v\n", t.Value, t.Label, t.Expiry, t.Scopes)
+		}
+		return nil
+	},
+}
+
+var tokenRevokeCmd = &cobra.Command{
+	Use:   "revoke <value>",
+	Short: "Remove a token from the jar",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		jar, err := admin.OpenJar(tokenJarPath)
+		if err != nil {
+			return err
+		}
+
+		return jar.Revoke(args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tokenCmd)
+	tokenCmd.AddCommand(tokenAddCmd, tokenListCmd, tokenRevokeCmd)
+
+	tokenCmd.PersistentFlags().StringVar(&tokenJarPath, "token-jar", "/var/lib/device-worker-ng/tokens.json", "path to the admin API's token jar")
+
+	tokenAddCmd.Flags().StringVar(&tokenLabel, "label", "", "human-readable label for the token")
+	tokenAddCmd.Flags().StringSliceVar(&tokenScopes, "scope", nil, "scope to grant (workloads:read, workloads:write, certs:rotate, root), may be repeated")
+	tokenAddCmd.Flags().DurationVar(&tokenTTL, "ttl", 0, "how long the token stays valid (0 means it never expires)")
+}
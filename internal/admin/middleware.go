@@ -0,0 +1,42 @@
+package admin
+
+import (
+	"net/http"
+	"strings"
+)
+
+// requireScope wraps next so it only runs for requests carrying a valid,
+// non-expired bearer token that holds scope. Missing/expired tokens get 401;
+// tokens lacking scope get 403.
+func (s *Server) requireScope(scope Scope, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value, ok := bearerToken(r)
+		if !ok {
+			http.Error(w, "missing bearer token", http.StatusUnauthorized)
+			return
+		}
+
+		token, ok := s.jar.Lookup(value)
+		if !ok {
+			http.Error(w, "token expired or unknown", http.StatusUnauthorized)
+			return
+		}
+
+		if !token.HasScope(scope) {
+			http.Error(w, "token missing required scope", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if !strings.HasPrefix(header, prefix) {
+		return "", false
+	}
+
+	return strings.TrimPrefix(header, prefix), true
+}
@@ -0,0 +1,173 @@
+// Package admin exposes a local HTTP API so operators can inspect and act on
+// a running device-worker-ng process without SSH: list/restart workloads,
+// inspect profile state, rotate certificates and scrape metrics.
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/tupyy/device-worker-ng/internal/tlsconfig"
+	"go.uber.org/zap"
+)
+
+// WorkloadsReader is the subset of the scheduler needed to answer
+// GET /workloads and POST /workloads/{id}/restart.
+type WorkloadsReader interface {
+	Workloads() []WorkloadStatus
+	RestartWorkload(id string) error
+}
+
+// WorkloadStatus is the admin API's view of a single workload.
+type WorkloadStatus struct {
+	ID    string `json:"id"`
+	State string `json:"state"`
+}
+
+// ProfilesReader is the subset of the profile manager needed to answer
+// GET /profiles.
+type ProfilesReader interface {
+	Profiles() map[string]string
+}
+
+// Server is the local admin HTTP API.
+type Server struct {
+	httpServer  *http.Server
+	jar         *Jar
+	workloads   WorkloadsReader
+	profiles    ProfilesReader
+	rotateCerts func()
+}
+
+// Config wires the admin server to the rest of the process.
+type Config struct {
+	Listen      string
+	Profile     *tlsconfig.Profile
+	Jar         *Jar
+	Workloads   WorkloadsReader
+	Profiles    ProfilesReader
+	RotateCerts func()
+	Metrics     http.Handler
+}
+
+// New builds a Server which is not yet listening; call Start to do so.
+func New(cfg Config) *Server {
+	s := &Server{
+		jar:         cfg.Jar,
+		workloads:   cfg.Workloads,
+		profiles:    cfg.Profiles,
+		rotateCerts: cfg.RotateCerts,
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/workloads", s.requireScope(ScopeWorkloadsRead, http.HandlerFunc(s.handleListWorkloads)))
+	mux.Handle("/workloads/", s.requireScope(ScopeWorkloadsWrite, http.HandlerFunc(s.handleRestartWorkload)))
+	mux.Handle("/profiles", s.requireScope(ScopeWorkloadsRead, http.HandlerFunc(s.handleListProfiles)))
+	mux.Handle("/certificates/rotate", s.requireScope(ScopeCertsRotate, http.HandlerFunc(s.handleRotateCertificates)))
+	if cfg.Metrics != nil {
+		mux.Handle("/metrics", s.requireScope(ScopeWorkloadsRead, cfg.Metrics))
+	}
+
+	s.httpServer = &http.Server{
+		Addr:    cfg.Listen,
+		Handler: mux,
+	}
+	if cfg.Profile != nil {
+		s.httpServer.TLSConfig = cfg.Profile.TLSConfig()
+	}
+
+	return s
+}
+
+// Start begins serving in the background and returns immediately.
+func (s *Server) Start(ctx context.Context) {
+	go func() {
+		var err error
+		if s.httpServer.TLSConfig != nil {
+			err = s.httpServer.ListenAndServeTLS("", "")
+		} else {
+			err = s.httpServer.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			zap.S().Errorw("admin server stopped", "error", err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		_ = s.Shutdown(context.Background())
+	}()
+}
+
+// Shutdown gracefully stops the server.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
+
+func (s *Server) handleListWorkloads(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.workloads.Workloads())
+}
+
+func (s *Server) handleRestartWorkload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := workloadIDFromPath(r.URL.Path)
+	if !ok {
+		http.Error(w, "missing workload id", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.workloads.RestartWorkload(id); err != nil {
+		http.Error(w, fmt.Sprintf("cannot restart workload: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) handleListProfiles(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.profiles.Profiles())
+}
+
+func (s *Server) handleRotateCertificates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.rotateCerts == nil {
+		http.Error(w, "certificate rotation is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	s.rotateCerts()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		zap.S().Errorw("cannot encode admin response", "error", err)
+	}
+}
+
+// workloadIDFromPath extracts the {id} segment from /workloads/{id}/restart.
+func workloadIDFromPath(path string) (string, bool) {
+	const prefix = "/workloads/"
+	const suffix = "/restart"
+
+	if len(path) <= len(prefix)+len(suffix) {
+		return "", false
+	}
+	if path[:len(prefix)] != prefix || path[len(path)-len(suffix):] != suffix {
+		return "", false
+	}
+
+	return path[len(prefix) : len(path)-len(suffix)], true
+}
@@ -0,0 +1,173 @@
+package admin
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Scope is a single permission a token can hold.
+type Scope string
+
+const (
+	ScopeWorkloadsRead  Scope = "workloads:read"
+	ScopeWorkloadsWrite Scope = "workloads:write"
+	ScopeCertsRotate    Scope = "certs:rotate"
+	ScopeRoot           Scope = "root"
+)
+
+// Token is an opaque bearer credential with an expiry and a set of scopes.
+type Token struct {
+	Value   string    `json:"value"`
+	Label   string    `json:"label"`
+	Expiry  time.Time `json:"expiry"`
+	Scopes  []Scope   `json:"scopes"`
+	Created time.Time `json:"created"`
+}
+
+func (t Token) expired() bool {
+	return !t.Expiry.IsZero() && time.Now().After(t.Expiry)
+}
+
+// HasScope reports whether the token grants scope, either directly or via
+// the catch-all ScopeRoot.
+func (t Token) HasScope(scope Scope) bool {
+	for _, s := range t.Scopes {
+		if s == ScopeRoot || s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Jar is an on-disk store of Tokens, persisted with 0600 perms so only the
+// worker's own user can read it.
+type Jar struct {
+	mu     sync.Mutex
+	path   string
+	tokens map[string]Token
+}
+
+// OpenJar loads path, creating an empty jar if it does not exist yet.
+func OpenJar(path string) (*Jar, error) {
+	jar := &Jar{path: path, tokens: make(map[string]Token)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return jar, jar.saveLocked()
+		}
+		return nil, fmt.Errorf("cannot read token jar: %w", err)
+	}
+
+	if len(data) == 0 {
+		return jar, nil
+	}
+
+	var tokens []Token
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("cannot parse token jar: %w", err)
+	}
+
+	for _, t := range tokens {
+		jar.tokens[t.Value] = t
+	}
+
+	return jar, nil
+}
+
+// Add mints a new token with scopes, valid for ttl (zero means it never
+// expires), and persists the jar.
+func (j *Jar) Add(label string, scopes []Scope, ttl time.Duration) (Token, error) {
+	value, err := randomToken()
+	if err != nil {
+		return Token{}, err
+	}
+
+	token := Token{
+		Value:   value,
+		Label:   label,
+		Scopes:  scopes,
+		Created: time.Now(),
+	}
+	if ttl > 0 {
+		token.Expiry = token.Created.Add(ttl)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.tokens[token.Value] = token
+	if err := j.saveLocked(); err != nil {
+		return Token{}, err
+	}
+
+	return token, nil
+}
+
+// Revoke removes a token from the jar and persists the change.
+func (j *Jar) Revoke(value string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	delete(j.tokens, value)
+	return j.saveLocked()
+}
+
+// List returns every token currently in the jar.
+func (j *Jar) List() []Token {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	tokens := make([]Token, 0, len(j.tokens))
+	for _, t := range j.tokens {
+		tokens = append(tokens, t)
+	}
+	return tokens
+}
+
+// Lookup returns the Token for value if it exists and is not expired. Scope
+// checks are the caller's responsibility (see Token.HasScope), so callers can
+// tell a missing/expired token (401) apart from one lacking a scope (403).
+func (j *Jar) Lookup(value string) (Token, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	token, ok := j.tokens[value]
+	if !ok || token.expired() {
+		return Token{}, false
+	}
+
+	return token, true
+}
+
+func (j *Jar) saveLocked() error {
+	tokens := make([]Token, 0, len(j.tokens))
+	for _, t := range j.tokens {
+		tokens = append(tokens, t)
+	}
+
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot encode token jar: %w", err)
+	}
+
+	tmp := j.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("cannot write token jar: %w", err)
+	}
+
+	return os.Rename(tmp, j.path)
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("cannot generate token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,150 @@
+package certificate
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"sync"
+)
+
+// Manager holds the current set of certificates used by the worker to talk to the
+// flotta operator and exposes them to whoever needs to build a TLS configuration.
+type Manager struct {
+	mu sync.RWMutex
+
+	roots *x509.CertPool
+
+	leaf *x509.Certificate
+	key  crypto.PrivateKey
+
+	certPEM []byte
+	keyPEM  []byte
+
+	// signature changes every time leaf/key are replaced. It is used by callers
+	// (e.g. the http client) to detect that the transport needs to be rebuilt.
+	signature []byte
+}
+
+// New creates a Manager from a set of PEM encoded CA roots, a PEM encoded leaf
+// certificate and a PEM encoded private key.
+func New(caRoots [][]byte, cert []byte, key []byte) (*Manager, error) {
+	pool := x509.NewCertPool()
+	for _, ca := range caRoots {
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("cannot parse ca root")
+		}
+	}
+
+	leaf, privKey, err := parseCertAndKey(cert, key)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manager{
+		roots:   pool,
+		leaf:    leaf,
+		key:     privKey,
+		certPEM: cert,
+		keyPEM:  key,
+	}
+	m.signature = computeSignature(cert, key)
+
+	return m, nil
+}
+
+// GetCertificates returns the current CA pool, leaf certificate and private key.
+func (m *Manager) GetCertificates() (*x509.CertPool, *x509.Certificate, crypto.PrivateKey) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.roots, m.leaf, m.key
+}
+
+// Signature returns a hash identifying the current leaf certificate and key.
+// Callers can compare successive signatures to detect a rotation.
+func (m *Manager) Signature() []byte {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.signature
+}
+
+// Replace swaps the leaf certificate and key for new ones in place, bumping
+// the signature. Unlike constructing a new Manager, this keeps every
+// existing holder of this *Manager (e.g. a running StartAutoRenewal
+// goroutine, or a tlsconfig.Profile reloading from disk) pointed at the up
+// to date certificate/key pair.
+func (m *Manager) Replace(cert, key []byte) error {
+	leaf, privKey, err := parseCertAndKey(cert, key)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.leaf = leaf
+	m.key = privKey
+	m.certPEM = cert
+	m.keyPEM = key
+	m.signature = computeSignature(cert, key)
+
+	return nil
+}
+
+func parseCertAndKey(cert, key []byte) (*x509.Certificate, crypto.PrivateKey, error) {
+	certBlock, _ := pem.Decode(cert)
+	if certBlock == nil {
+		return nil, nil, fmt.Errorf("cannot decode certificate pem")
+	}
+
+	leaf, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot parse certificate: %w", err)
+	}
+
+	privKey, err := parsePrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return leaf, privKey, nil
+}
+
+func parsePrivateKey(key []byte) (crypto.PrivateKey, error) {
+	keyBlock, _ := pem.Decode(key)
+	if keyBlock == nil {
+		return nil, fmt.Errorf("cannot decode private key pem")
+	}
+
+	if k, err := x509.ParseECPrivateKey(keyBlock.Bytes); err == nil {
+		return k, nil
+	}
+
+	if k, err := x509.ParsePKCS1PrivateKey(keyBlock.Bytes); err == nil {
+		return k, nil
+	}
+
+	k, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse private key: %w", err)
+	}
+
+	switch k.(type) {
+	case *ecdsa.PrivateKey, *rsa.PrivateKey:
+		return k, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", k)
+	}
+}
+
+func computeSignature(cert, key []byte) []byte {
+	h := sha256.New()
+	h.Write(cert)
+	h.Write(key)
+	return h.Sum(nil)
+}
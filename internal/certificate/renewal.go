@@ -0,0 +1,274 @@
+package certificate
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// renewalFraction is the divisor of the certificate validity period used
+	// to decide when to renew: a renewal is attempted once less than
+	// lifetime/renewalFraction of it remains.
+	renewalFraction = 3
+
+	// jitterWindow bounds the random delay added to the renewal check so that a
+	// fleet of devices enrolled at the same time does not hammer the operator
+	// all at once.
+	jitterWindow = 10 * time.Minute
+
+	minCheckInterval = time.Minute
+
+	// defaultCheckIntervalFraction sets the default check cadence, when
+	// AutoRenewalConfig.CheckInterval is zero, to a fraction of the
+	// certificate's validity period, so a long-lived certificate is not
+	// polled needlessly often and a short-lived one is still checked well
+	// before renewalFraction makes it due for renewal.
+	defaultCheckIntervalFraction = 20
+
+	defaultBackoff  = 30 * time.Second
+	maxBackoff      = 30 * time.Minute
+	defaultFilePerm = 0o600
+)
+
+// Renewer obtains a new signed certificate for the device, authenticating the
+// request with the certificate/key currently held by the Manager. Any error
+// it returns, whether the CA rejected the renewal (e.g. the certificate
+// already expired) or the request failed for another reason, makes the
+// Manager fall back to re-enrollment.
+type Renewer interface {
+	RenewCertificate(ctx context.Context, deviceID string, certPEM, keyPEM []byte) (newCertPEM, newKeyPEM []byte, err error)
+}
+
+// Enroller re-enrolls the device from scratch. It is used as a fallback when
+// the CA refuses to renew the existing certificate.
+type Enroller interface {
+	Reenrol(ctx context.Context, deviceID string) (certPEM, keyPEM []byte, err error)
+}
+
+// RotationEvent is sent on the channel returned by StartAutoRenewal every time the
+// on-disk certificate/key pair is replaced.
+type RotationEvent struct {
+	Signature []byte
+	RenewedAt time.Time
+}
+
+// AutoRenewalConfig configures the background renewal loop.
+type AutoRenewalConfig struct {
+	DeviceID string
+	CertPath string
+	KeyPath  string
+
+	// CheckInterval is how often the loop inspects the leaf's NotAfter.
+	// Defaults to a value derived from the certificate lifetime if zero.
+	CheckInterval time.Duration
+}
+
+// StartAutoRenewal starts a background goroutine which periodically checks
+// whether the current leaf certificate is close to expiring and, if so,
+// renews it through renewer (falling back to enroller when renewer fails). It
+// returns a channel on which rotation events are published so other
+// subsystems (edge controller, profile manager) can react to a certificate
+// swap, a forceRenew function which triggers a renewal regardless of expiry,
+// and a cancel function which stops the loop.
+func (m *Manager) StartAutoRenewal(ctx context.Context, renewer Renewer, enroller Enroller, cfg AutoRenewalConfig) (rotated <-chan RotationEvent, forceRenew func(), cancel context.CancelFunc) {
+	runCtx, cancelFunc := context.WithCancel(ctx)
+
+	events := make(chan RotationEvent, 1)
+	force := make(chan struct{}, 1)
+
+	go m.renewalLoop(runCtx, renewer, enroller, cfg, events, force)
+
+	forceRenew = func() {
+		select {
+		case force <- struct{}{}:
+		default:
+		}
+	}
+
+	return events, forceRenew, cancelFunc
+}
+
+func (m *Manager) renewalLoop(ctx context.Context, renewer Renewer, enroller Enroller, cfg AutoRenewalConfig, events chan<- RotationEvent, force <-chan struct{}) {
+	defer close(events)
+
+	backoff := defaultBackoff
+
+	for {
+		wait := m.timeUntilNextCheck(cfg.CheckInterval)
+
+		forced := false
+		select {
+		case <-ctx.Done():
+			return
+		case <-force:
+			// forced rotation requested through the RPC entry point: renew
+			// regardless of whether the leaf is actually close to expiring.
+			forced = true
+		case <-time.After(wait):
+		}
+
+		if !forced && !m.dueForRenewal() {
+			continue
+		}
+
+		cert, key, err := m.renew(ctx, renewer, enroller, cfg.DeviceID)
+		if err != nil {
+			zap.S().Errorw("certificate renewal failed, backing off", "error", err, "backoff", backoff)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = nextBackoff(backoff)
+			continue
+		}
+
+		backoff = defaultBackoff
+
+		if err := writeCertAndKey(cfg.CertPath, cert, cfg.KeyPath, key); err != nil {
+			zap.S().Errorw("cannot persist renewed certificate", "error", err)
+			continue
+		}
+
+		if err := m.Replace(cert, key); err != nil {
+			zap.S().Errorw("cannot reload renewed certificate", "error", err)
+			continue
+		}
+
+		zap.S().Info("certificate renewed")
+
+		select {
+		case events <- RotationEvent{Signature: m.Signature(), RenewedAt: time.Now()}:
+		default:
+			zap.S().Warn("rotation event dropped, no subscriber reading the channel")
+		}
+	}
+}
+
+// renew asks renewer for a new certificate, falling back to a full
+// re-enrollment whenever the renewal request fails. A rejection by the CA is
+// not told apart from any other failure.
+func (m *Manager) renew(ctx context.Context, renewer Renewer, enroller Enroller, deviceID string) (cert, key []byte, err error) {
+	cert, key, err = renewer.RenewCertificate(ctx, deviceID, m.currentCertPEM(), m.currentKeyPEM())
+	if err == nil {
+		return cert, key, nil
+	}
+
+	zap.S().Warnw("certificate renewal failed, falling back to re-enrollment", "error", err)
+
+	if enroller == nil {
+		return nil, nil, fmt.Errorf("renewal failed and no enroller configured: %w", err)
+	}
+
+	cert, key, err = enroller.Reenrol(ctx, deviceID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("re-enrollment after failed renewal failed: %w", err)
+	}
+
+	return cert, key, nil
+}
+
+func (m *Manager) dueForRenewal() bool {
+	_, leaf, _ := m.GetCertificates()
+	if leaf == nil {
+		return false
+	}
+
+	lifetime := leaf.NotAfter.Sub(leaf.NotBefore)
+	renewAt := leaf.NotAfter.Add(-lifetime / renewalFraction)
+
+	return !time.Now().Before(renewAt)
+}
+
+// timeUntilNextCheck returns how long to sleep before the next expiry check,
+// adding jitter so a fleet of devices does not wake up at the same time.
+func (m *Manager) timeUntilNextCheck(interval time.Duration) time.Duration {
+	if interval <= 0 {
+		interval = m.defaultCheckInterval()
+	}
+
+	return interval + jitter(jitterWindow)
+}
+
+// defaultCheckInterval derives a check cadence from the current leaf
+// certificate's validity period (see defaultCheckIntervalFraction), falling
+// back to minCheckInterval when there is no leaf yet or the derived interval
+// would be too short to be useful.
+func (m *Manager) defaultCheckInterval() time.Duration {
+	_, leaf, _ := m.GetCertificates()
+	if leaf == nil {
+		return minCheckInterval
+	}
+
+	lifetime := leaf.NotAfter.Sub(leaf.NotBefore)
+	interval := lifetime / defaultCheckIntervalFraction
+	if interval < minCheckInterval {
+		return minCheckInterval
+	}
+
+	return interval
+}
+
+func jitter(window time.Duration) time.Duration {
+	if window <= 0 {
+		return 0
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(window)))
+	if err != nil {
+		return 0
+	}
+
+	return time.Duration(n.Int64())
+}
+
+func nextBackoff(current time.Duration) time.Duration {
+	next := current * 2
+	if next > maxBackoff {
+		return maxBackoff
+	}
+	return next
+}
+
+func (m *Manager) currentKeyPEM() []byte {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.keyPEM
+}
+
+func (m *Manager) currentCertPEM() []byte {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.certPEM
+}
+
+// writeCertAndKey atomically rewrites the certificate and key files on disk so
+// a concurrent reader never observes a torn write.
+func writeCertAndKey(certPath string, cert []byte, keyPath string, key []byte) error {
+	if err := atomicWrite(certPath, cert); err != nil {
+		return fmt.Errorf("cannot write certificate: %w", err)
+	}
+
+	if err := atomicWrite(keyPath, key); err != nil {
+		return fmt.Errorf("cannot write private key: %w", err)
+	}
+
+	return nil
+}
+
+func atomicWrite(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, defaultFilePerm); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,130 @@
+package certificate
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestManager builds a Manager around a self-signed leaf certificate with
+// the given validity period, so tests can exercise logic derived from
+// NotBefore/NotAfter without a real CA.
+func newTestManager(t *testing.T, notBefore time.Time, lifetime time.Duration) *Manager {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("cannot generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    notBefore,
+		NotAfter:     notBefore.Add(lifetime),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("cannot create certificate: %v", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("cannot marshal key: %v", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	m, err := New(nil, certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("cannot create manager: %v", err)
+	}
+
+	return m
+}
+
+func TestDefaultCheckIntervalDerivesFromLifetime(t *testing.T) {
+	lifetime := 20 * time.Hour
+	m := newTestManager(t, time.Now(), lifetime)
+
+	got := m.defaultCheckInterval()
+	want := lifetime / defaultCheckIntervalFraction
+
+	if got != want {
+		t.Errorf("defaultCheckInterval() = %v, want %v", got, want)
+	}
+}
+
+func TestDefaultCheckIntervalFloorsAtMinimum(t *testing.T) {
+	m := newTestManager(t, time.Now(), time.Minute)
+
+	if got := m.defaultCheckInterval(); got != minCheckInterval {
+		t.Errorf("defaultCheckInterval() = %v, want floor %v", got, minCheckInterval)
+	}
+}
+
+func TestDueForRenewal(t *testing.T) {
+	lifetime := 30 * time.Hour
+
+	notYetDue := newTestManager(t, time.Now(), lifetime)
+	if notYetDue.dueForRenewal() {
+		t.Error("dueForRenewal() = true for a freshly issued certificate, want false")
+	}
+
+	due := newTestManager(t, time.Now().Add(-2*lifetime/renewalFraction), lifetime)
+	if !due.dueForRenewal() {
+		t.Error("dueForRenewal() = false for a certificate past its renewal point, want true")
+	}
+}
+
+func TestNextBackoff(t *testing.T) {
+	cases := []struct {
+		current time.Duration
+		want    time.Duration
+	}{
+		{current: defaultBackoff, want: 2 * defaultBackoff},
+		{current: maxBackoff, want: maxBackoff},
+		{current: maxBackoff / 2, want: maxBackoff},
+	}
+
+	for _, tc := range cases {
+		if got := nextBackoff(tc.current); got != tc.want {
+			t.Errorf("nextBackoff(%v) = %v, want %v", tc.current, got, tc.want)
+		}
+	}
+}
+
+func TestAtomicWriteReplacesExistingFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cert.pem")
+
+	if err := os.WriteFile(path, []byte("old"), defaultFilePerm); err != nil {
+		t.Fatalf("cannot seed file: %v", err)
+	}
+
+	if err := atomicWrite(path, []byte("new")); err != nil {
+		t.Fatalf("atomicWrite() error = %v", err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("cannot read written file: %v", err)
+	}
+	if string(got) != "new" {
+		t.Errorf("file content = %q, want %q", got, "new")
+	}
+
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temp file %s.tmp should not remain after rename", path)
+	}
+}
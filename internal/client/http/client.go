@@ -4,9 +4,10 @@ import (
 	"bytes"
 	"context"
 	"crypto/ecdsa"
-	"crypto/rsa"
-	"crypto/tls"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
 	"encoding/pem"
 	"fmt"
@@ -16,22 +17,26 @@ import (
 	"time"
 
 	"github.com/project-flotta/flotta-operator/models"
-	"github.com/tupyy/device-worker-ng/internal/certificate"
 	"github.com/tupyy/device-worker-ng/internal/entities"
+	"github.com/tupyy/device-worker-ng/internal/metrics"
+	"github.com/tupyy/device-worker-ng/internal/tlsconfig"
 	"go.uber.org/zap"
 )
 
 const (
-	certificateKey = "certificate"
-	rootUrl        = "/api/flotta-management/v1"
+	certificateKey      = "certificate"
+	rootUrl             = "/api/flotta-management/v1"
+	renewCertActionType = "renew-cert"
 )
 
 // transportWrapper is a wrapper for transport. It can be used as a middleware.
 type transportWrapper func(http.RoundTripper) http.RoundTripper
 
 type Client struct {
-	// certMananger holds the Certificate Manager
-	certMananger *certificate.Manager
+	// profile holds the cluster TLS profile used to talk to the flotta
+	// operator. It owns the certificate/key pair and knows how to build the
+	// *tls.Config for this client, including hot-reloaded renewals.
+	profile *tlsconfig.Profile
 
 	// certificateSignature holds the signature of the client certificate which is used in TLS config.
 	// It is used to check if certificates had been updated following registration process.
@@ -44,11 +49,16 @@ type Client struct {
 
 	// transport is the transport which make the actual request
 	transport http.RoundTripper
+
+	// retryPolicies holds the per-method retry/backoff configuration.
+	retryPolicies RetryPolicies
 }
 
-func New(path string, certManager *certificate.Manager) (*Client, error) {
-	if certManager == nil {
-		return nil, fmt.Errorf("Certificate manager is missing")
+// New builds a Client. registry may be nil, in which case no metrics are
+// recorded.
+func New(path string, profile *tlsconfig.Profile, retryPolicies RetryPolicies, registry *metrics.Registry) (*Client, error) {
+	if profile == nil {
+		return nil, fmt.Errorf("TLS profile is missing")
 	}
 
 	url, err := url.Parse(path)
@@ -57,15 +67,20 @@ func New(path string, certManager *certificate.Manager) (*Client, error) {
 	}
 
 	// TODO dynamically set based on log level
-	transportWrapper := make([]transportWrapper, 0, 1)
+	transportWrapper := make([]transportWrapper, 0, 3)
 	logWrapper := &logTransportWrapper{}
 	transportWrapper = append(transportWrapper, logWrapper.Wrap)
+	metricsWrapper := &metricsTransportWrapper{registry: registry}
+	transportWrapper = append(transportWrapper, metricsWrapper.Wrap)
+	retryWrapper := &retryTransportWrapper{}
+	transportWrapper = append(transportWrapper, retryWrapper.Wrap)
 
 	return &Client{
 		serverURL:            url,
-		certMananger:         certManager,
+		profile:              profile,
 		certificateSignature: []byte{},
 		transportWrappers:    transportWrapper,
+		retryPolicies:        retryPolicies,
 	}, nil
 }
 
@@ -76,7 +91,7 @@ func (c *Client) Enrol(ctx context.Context, deviceID string, enrolInfo entities.
 		Header("Content-Type", "application/json").
 		Url(fmt.Sprintf("%s/%s/data/%s/out", c.serverURL.String(), rootUrl, deviceID)).
 		Body(enrolInfo).
-		Build(ctx)
+		Build(withRetryPolicy(withAction(ctx, enrolActionType), c.retryPolicies.Enrol))
 
 	if err != nil {
 		return fmt.Errorf("cannot create enrollment request '%w'", err)
@@ -101,7 +116,7 @@ func (c *Client) Register(ctx context.Context, deviceID string, registerInfo ent
 		Header("Content-Type", "application/json").
 		Url(fmt.Sprintf("%s/%s/data/%s/out", c.serverURL.String(), rootUrl, deviceID)).
 		Body(registerInfo).
-		Build(ctx)
+		Build(withRetryPolicy(withAction(ctx, registerActionType), c.retryPolicies.Register))
 
 	if err != nil {
 		return entities.RegistrationResponse{}, fmt.Errorf("cannot create registration request '%w'", err)
@@ -137,7 +152,7 @@ func (c *Client) Heartbeat(ctx context.Context, deviceID string, heartbeat entit
 		Url(fmt.Sprintf("%s/%s/data/%s/out", c.serverURL.String(), rootUrl, deviceID)).
 		Body(heartbeat).
 		Header("Content-Type", "application/json").
-		Build(ctx)
+		Build(withRetryPolicy(withAction(ctx, heartbeatActionType), c.retryPolicies.Heartbeat))
 
 	if err != nil {
 		return fmt.Errorf("cannot create heartbeat request '%w'", err)
@@ -162,7 +177,7 @@ func (c *Client) GetConfiguration(ctx context.Context, deviceID string) (entitie
 		Action(configurationActionType).
 		Header("Content-Type", "application/json").
 		Url(fmt.Sprintf("%s/%s/data/%s/in", c.serverURL.String(), rootUrl, deviceID)).
-		Build(ctx)
+		Build(withRetryPolicy(withAction(ctx, configurationActionType), c.retryPolicies.Configuration))
 
 	if err != nil {
 		return entities.DeviceConfiguration{}, fmt.Errorf("cannot create configuration request '%w'", err)
@@ -188,7 +203,7 @@ func (c *Client) GetConfiguration(ctx context.Context, deviceID string) (entitie
 		return entities.DeviceConfiguration{}, fmt.Errorf("cannot find configuration data in payload")
 	}
 
-	var m models.DeviceConfiguration
+	var m deviceConfigurationModel
 
 	j, err := json.Marshal(conf)
 	if err != nil {
@@ -203,8 +218,141 @@ func (c *Client) GetConfiguration(ctx context.Context, deviceID string) (entitie
 	return configurationModel2Entity(m), nil
 }
 
+// deviceConfigurationModel is the wire shape of the "configuration" payload
+// nested in a MessageResponse, covering the device-worker-ng-specific
+// extensions to the operator's device configuration: which workloads to
+// stream logs for and where to route profile notifications.
+type deviceConfigurationModel struct {
+	LogTargets     []string                      `json:"logTargets,omitempty"`
+	NotifierRoutes []entities.NotifierRouteConfig `json:"notifierRoutes,omitempty"`
+}
+
+// configurationModel2Entity translates the wire configuration model into the
+// entities.DeviceConfiguration consumed by the edge controller.
+func configurationModel2Entity(m deviceConfigurationModel) entities.DeviceConfiguration {
+	return entities.DeviceConfiguration{
+		LogTargets:     m.LogTargets,
+		NotifierRoutes: m.NotifierRoutes,
+	}
+}
+
+// RenewCertificate requests a renewed client certificate from the operator,
+// authenticating with the certificate/key currently held by the caller and
+// presenting a freshly generated CSR. It implements certificate.Renewer so it
+// can be plugged directly into the certificate Manager's auto-renewal loop.
+func (c *Client) RenewCertificate(ctx context.Context, deviceID string, certPEM, keyPEM []byte) (newCertPEM, newKeyPEM []byte, err error) {
+	csrPEM, newKeyPEM, err := generateCSR(deviceID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot generate renewal csr: %w", err)
+	}
+
+	request, err := newRequestBuilder().
+		Type(postDataMessageForDeviceType).
+		Action(renewCertActionType).
+		Header("Content-Type", "application/json").
+		Url(fmt.Sprintf("%s/%s/data/%s/out", c.serverURL.String(), rootUrl, deviceID)).
+		Body(entities.CertificateRenewalRequest{CSR: csrPEM}).
+		Build(withAction(ctx, renewCertActionType))
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot create renewal request '%w'", err)
+	}
+
+	res, err := c.do(request)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot renew certificate '%w'", err)
+	}
+
+	if res.StatusCode >= 400 {
+		return nil, nil, fmt.Errorf("certificate renewal rejected. code: %d", res.StatusCode)
+	}
+
+	message, err := c.processResponse(res)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certMap, ok := message.Content.(map[string]interface{})
+	if !ok {
+		return nil, nil, fmt.Errorf("payload content is not a map")
+	}
+
+	cert, ok := certMap[certificateKey]
+	if !ok {
+		return nil, nil, fmt.Errorf("cannot get certificate from payload")
+	}
+
+	return bytes.NewBufferString(cert.(string)).Bytes(), newKeyPEM, nil
+}
+
+// Reenrol performs a full enrol+register handshake and returns a freshly
+// signed certificate. It is used as a fallback when RenewCertificate is
+// rejected by the CA, e.g. because the current certificate already expired.
+func (c *Client) Reenrol(ctx context.Context, deviceID string) (certPEM, keyPEM []byte, err error) {
+	csrPEM, keyPEM, err := generateCSR(deviceID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot generate csr for re-enrollment: %w", err)
+	}
+
+	if err := c.Enrol(ctx, deviceID, entities.EnrolementInfo{}); err != nil {
+		return nil, nil, fmt.Errorf("cannot enrol device '%w'", err)
+	}
+
+	resp, err := c.Register(ctx, deviceID, entities.RegistrationInfo{CSR: csrPEM})
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot register device '%w'", err)
+	}
+
+	return resp.SignedCSR, keyPEM, nil
+}
+
+// generateCSR creates a fresh ECDSA key pair and a PEM encoded CSR for it,
+// used both for renewal and for re-enrollment.
+func generateCSR(deviceID string) (csrPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot generate key: %w", err)
+	}
+
+	template := x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: deviceID},
+	}
+
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &template, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot create csr: %w", err)
+	}
+
+	csrBuf := new(bytes.Buffer)
+	if err := pem.Encode(csrBuf, &pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER}); err != nil {
+		return nil, nil, fmt.Errorf("cannot encode csr: %w", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot marshal key: %w", err)
+	}
+
+	keyBuf := new(bytes.Buffer)
+	if err := pem.Encode(keyBuf, &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}); err != nil {
+		return nil, nil, fmt.Errorf("cannot encode key: %w", err)
+	}
+
+	return csrBuf.Bytes(), keyBuf.Bytes(), nil
+}
+
+// defaultClientTimeout bounds a request which carries no retry policy (or a
+// disabled one), so a single attempt can never hang forever.
+const defaultClientTimeout = 2 * time.Second
+
+// clientTimeoutSlack is added on top of a RetryPolicy's RetryTimeout so the
+// outer http.Client.Timeout never fires before retryRoundTripper's own
+// deadline does; it only exists to catch the case where that loop gets stuck
+// past its own bookkeeping.
+const clientTimeoutSlack = 5 * time.Second
+
 func (c *Client) do(request *http.Request) (*http.Response, error) {
-	client, err := c.getClient()
+	client, err := c.getClient(request)
 	if err != nil {
 		return nil, err
 	}
@@ -214,34 +362,34 @@ func (c *Client) do(request *http.Request) (*http.Response, error) {
 
 // getClient returns a real http.Client created with our transport.
 // It checks if certifcates signatures changed and if true it recreates a new transport.
-func (c *Client) getClient() (*http.Client, error) {
-	if !bytes.Equal(c.certificateSignature, c.certMananger.Signature()) {
+// The client's Timeout is derived from the RetryPolicy attached to request's
+// context so it bounds the whole retry budget, not just a single attempt.
+func (c *Client) getClient(request *http.Request) (*http.Client, error) {
+	if !bytes.Equal(c.certificateSignature, c.profile.Signature()) {
 		zap.S().Info("certificates changed. recreate transport")
-		t, err := c.createTransport()
-		if err != nil {
-			return nil, err
-		}
+		t := c.createTransport()
 
-		c.certificateSignature = c.certMananger.Signature()
+		c.certificateSignature = c.profile.Signature()
 
 		c.transport = t
 	}
 
+	timeout := defaultClientTimeout
+	if policy := retryPolicyFromContext(request.Context()); policy.RetryTimeout > 0 {
+		timeout = policy.RetryTimeout + clientTimeoutSlack
+	}
+
 	return &http.Client{
 		Transport: c.transport,
-		Timeout:   2 * time.Second, //TODO to be parametrized
+		Timeout:   timeout,
 	}, nil
 
 }
 
-func (c *Client) createTransport() (result http.RoundTripper, err error) {
-	var tlsConfig *tls.Config
-
-	tlsConfig, err = c.createTLSConfig()
-
-	result = &http.Transport{
+func (c *Client) createTransport() http.RoundTripper {
+	var result http.RoundTripper = &http.Transport{
 		Proxy:           http.ProxyFromEnvironment,
-		TLSClientConfig: tlsConfig,
+		TLSClientConfig: c.profile.TLSConfig(),
 	}
 
 	// call the other wrappers backwards
@@ -249,46 +397,7 @@ func (c *Client) createTransport() (result http.RoundTripper, err error) {
 		result = c.transportWrappers[i](result)
 	}
 
-	return result, err
-}
-
-func (c *Client) createTLSConfig() (*tls.Config, error) {
-	caRoot, cert, key := c.certMananger.GetCertificates()
-
-	config := tls.Config{
-		RootCAs: caRoot,
-	}
-
-	certPEM := new(bytes.Buffer)
-	err := pem.Encode(certPEM, &pem.Block{
-		Type:  "CERTIFICATE",
-		Bytes: cert.Raw,
-	})
-
-	privKeyPEM := new(bytes.Buffer)
-	switch t := key.(type) {
-	case *ecdsa.PrivateKey:
-		res, _ := x509.MarshalECPrivateKey(t)
-		_ = pem.Encode(privKeyPEM, &pem.Block{
-			Type:  "EC PRIVATE KEY",
-			Bytes: res,
-		})
-	case *rsa.PrivateKey:
-		_ = pem.Encode(privKeyPEM, &pem.Block{
-			Type:  "RSA PRIVATE KEY",
-			Bytes: x509.MarshalPKCS1PrivateKey(t),
-		})
-	}
-
-	//
-	cc, err := tls.X509KeyPair(certPEM.Bytes(), privKeyPEM.Bytes())
-	if err != nil {
-		return nil, fmt.Errorf("cannot create x509 key pair: %w", err)
-	}
-
-	config.Certificates = []tls.Certificate{cc}
-
-	return &config, nil
+	return result
 }
 
 func (c *Client) processResponse(res *http.Response) (models.MessageResponse, error) {
@@ -0,0 +1,137 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tupyy/device-worker-ng/internal/entities"
+	"github.com/tupyy/device-worker-ng/internal/logbroker"
+	"go.uber.org/zap"
+)
+
+const logsActionType = "logs"
+
+const (
+	defaultLogBatchSize     = 100
+	defaultLogFlushInterval = 5 * time.Second
+
+	// shutdownFlushTimeout bounds the final flush issued once shipLogs'
+	// context is done (subscription cancelled) or the broker closed its
+	// channel. It cannot reuse that context since it is already cancelled -
+	// a fresh one is used instead so the tail batch still has a chance to
+	// reach the operator.
+	shutdownFlushTimeout = 10 * time.Second
+)
+
+// LogSubscription is an active log stream being shipped to the operator.
+// Cancel tears it down, unsubscribing from the broker and stopping the
+// shipping goroutine; the edge controller calls it when the workload drops
+// out of the operator's LogTargets list and on shutdown. A workload exiting
+// does not cancel its subscription.
+type LogSubscription struct {
+	cancel context.CancelFunc
+}
+
+// Cancel stops shipping logs for this subscription.
+func (s *LogSubscription) Cancel() {
+	s.cancel()
+}
+
+// SubscribeLogs follows broker's records matching selector and ships them to
+// the operator in batches. It returns immediately; delivery happens on a
+// background goroutine until the returned subscription is cancelled.
+func (c *Client) SubscribeLogs(ctx context.Context, deviceID string, broker *logbroker.Broker, selector logbroker.Selector) *LogSubscription {
+	_, records, cancelSub := broker.Subscribe(selector)
+
+	subCtx, cancel := context.WithCancel(ctx)
+	go c.shipLogs(subCtx, deviceID, records, cancelSub)
+
+	return &LogSubscription{cancel: cancel}
+}
+
+func (c *Client) shipLogs(ctx context.Context, deviceID string, records <-chan logbroker.Record, cancelSub func()) {
+	defer cancelSub()
+
+	batch := make([]entities.LogRecord, 0, defaultLogBatchSize)
+	ticker := time.NewTicker(defaultLogFlushInterval)
+	defer ticker.Stop()
+
+	flushWith := func(flushCtx context.Context) {
+		if len(batch) == 0 {
+			return
+		}
+
+		if err := c.PublishLogs(flushCtx, deviceID, batch); err != nil {
+			zap.S().Errorw("cannot publish workload logs", "error", err)
+		}
+
+		batch = batch[:0]
+	}
+	flush := func() { flushWith(ctx) }
+
+	// shutdownFlush flushes the tail batch with a fresh context, since ctx
+	// is already done by the time either shutdown branch below runs and
+	// would otherwise fail every publish with "context canceled".
+	shutdownFlush := func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), shutdownFlushTimeout)
+		defer cancel()
+		flushWith(flushCtx)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			shutdownFlush()
+			return
+		case record, ok := <-records:
+			if !ok {
+				shutdownFlush()
+				return
+			}
+
+			batch = append(batch, toLogRecordEntity(record))
+			if len(batch) >= defaultLogBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func toLogRecordEntity(record logbroker.Record) entities.LogRecord {
+	return entities.LogRecord{
+		WorkloadID: record.WorkloadID,
+		Stream:     string(record.Stream),
+		Timestamp:  record.Timestamp,
+		Sequence:   record.Sequence,
+		Data:       record.Data,
+	}
+}
+
+// PublishLogs POSTs a batch of workload log records to the operator.
+func (c *Client) PublishLogs(ctx context.Context, deviceID string, records []entities.LogRecord) error {
+	request, err := newRequestBuilder().
+		Type(postDataMessageForDeviceType).
+		Action(logsActionType).
+		Header("Content-Type", "application/json").
+		Url(fmt.Sprintf("%s/%s/data/%s/logs/out", c.serverURL.String(), rootUrl, deviceID)).
+		Body(entities.LogBatch{Records: records}).
+		Build(withRetryPolicy(withAction(ctx, logsActionType), c.retryPolicies.Logs))
+
+	if err != nil {
+		return fmt.Errorf("cannot create publish logs request '%w'", err)
+	}
+
+	resp, err := c.do(request)
+	if err != nil {
+		return fmt.Errorf("cannot publish logs '%w'", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("cannot publish logs. code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/tupyy/device-worker-ng/internal/metrics"
+)
+
+type actionKey struct{}
+
+// withAction attaches the requestBuilder action type to ctx so
+// metricsTransportWrapper can label the request it ends up wrapping.
+func withAction(ctx context.Context, action string) context.Context {
+	return context.WithValue(ctx, actionKey{}, action)
+}
+
+func actionFromContext(ctx context.Context) string {
+	action, ok := ctx.Value(actionKey{}).(string)
+	if !ok {
+		return "unknown"
+	}
+	return action
+}
+
+// metricsTransportWrapper records request count and latency per action type
+// on registry. It is a no-op transportWrapper when registry is nil.
+type metricsTransportWrapper struct {
+	registry *metrics.Registry
+}
+
+func (w *metricsTransportWrapper) Wrap(next http.RoundTripper) http.RoundTripper {
+	if w.registry == nil {
+		return next
+	}
+	return &metricsRoundTripper{next: next, registry: w.registry}
+}
+
+type metricsRoundTripper struct {
+	next     http.RoundTripper
+	registry *metrics.Registry
+}
+
+func (r *metricsRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	action := actionFromContext(req.Context())
+	start := time.Now()
+
+	resp, err := r.next.RoundTrip(req)
+
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	r.registry.ObserveHTTPRequest(action, status, time.Since(start))
+
+	return resp, err
+}
@@ -0,0 +1,213 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"math/big"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RetryPolicy configures how a single request kind is retried by
+// retryTransportWrapper.
+type RetryPolicy struct {
+	// Sleep is the initial delay between attempts.
+	Sleep time.Duration
+
+	// RetryTimeout is the overall wall-clock budget for all attempts of a
+	// single call. Once elapsed, the last response/error is returned as-is.
+	RetryTimeout time.Duration
+
+	// MaxSleep caps the exponential backoff growth.
+	MaxSleep time.Duration
+}
+
+// noRetryPolicy disables retries: a single attempt is made.
+var noRetryPolicy = RetryPolicy{}
+
+// RetryPolicies holds the per-method retry configuration used by Client.
+type RetryPolicies struct {
+	Enrol         RetryPolicy
+	Register      RetryPolicy
+	Heartbeat     RetryPolicy
+	Configuration RetryPolicy
+	Logs          RetryPolicy
+}
+
+type retryPolicyKey struct{}
+
+// withRetryPolicy attaches policy to ctx so retryTransportWrapper can pick it
+// up when the request built from ctx reaches the transport.
+func withRetryPolicy(ctx context.Context, policy RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryPolicyKey{}, policy)
+}
+
+func retryPolicyFromContext(ctx context.Context) RetryPolicy {
+	policy, ok := ctx.Value(retryPolicyKey{}).(RetryPolicy)
+	if !ok {
+		return noRetryPolicy
+	}
+	return policy
+}
+
+// retryTransportWrapper is a transportWrapper which retries a request
+// according to the RetryPolicy found in its context, distinguishing
+// retryable failures (network errors, 5xx, 429) from terminal ones (other
+// 4xx).
+type retryTransportWrapper struct{}
+
+func (w *retryTransportWrapper) Wrap(next http.RoundTripper) http.RoundTripper {
+	return &retryRoundTripper{next: next}
+}
+
+type retryRoundTripper struct {
+	next http.RoundTripper
+}
+
+func (r *retryRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	policy := retryPolicyFromContext(req.Context())
+	if policy.RetryTimeout <= 0 {
+		return r.next.RoundTrip(req)
+	}
+
+	deadline := time.Now().Add(policy.RetryTimeout)
+	sleep := policy.Sleep
+	if sleep <= 0 {
+		sleep = time.Second
+	}
+
+	attempt := 0
+	start := time.Now()
+
+	for {
+		attempt++
+
+		attemptReq, err := freshBody(req)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := r.next.RoundTrip(attemptReq)
+		if !isRetryable(resp, err) {
+			return resp, err
+		}
+
+		wait := sleep
+		if resp != nil {
+			if retryAfter, ok := retryAfterDelay(resp); ok {
+				wait = retryAfter
+			}
+		}
+
+		if time.Now().Add(wait).After(deadline) {
+			return resp, err
+		}
+
+		zap.S().Warnw("retrying request",
+			"url", req.URL.String(),
+			"attempt", attempt,
+			"elapsed", time.Since(start),
+			"wait", wait,
+			"error", err,
+		)
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(jitter(wait)):
+		}
+
+		sleep = nextSleep(sleep, policy.MaxSleep)
+	}
+}
+
+// freshBody returns a shallow copy of req with its body reader reset via
+// GetBody, so a request with a body (Enrol, Register, Heartbeat,
+// PublishLogs) can be replayed on retry instead of sending the body already
+// drained by a previous attempt. Requests without a body (req.GetBody == nil)
+// are returned unchanged.
+func freshBody(req *http.Request) (*http.Request, error) {
+	if req.GetBody == nil {
+		return req, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+
+	clone := req.Clone(req.Context())
+	clone.Body = body
+	return clone, nil
+}
+
+// isRetryable classifies a response/error pair. Network errors and 5xx/429
+// responses are retryable; any other 4xx is terminal.
+func isRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	if resp == nil {
+		return false
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+
+	return resp.StatusCode >= 500
+}
+
+func retryAfterDelay(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	header := resp.Header.Get("Retry-After")
+	if header == "" {
+		return 0, false
+	}
+
+	seconds, err := strconv.Atoi(header)
+	if err != nil {
+		return 0, false
+	}
+
+	return time.Duration(seconds) * time.Second, true
+}
+
+func nextSleep(current, maxSleep time.Duration) time.Duration {
+	next := current * 2
+	if maxSleep > 0 && next > maxSleep {
+		return maxSleep
+	}
+	return next
+}
+
+// jitter adds up to 20% random variance to wait so a fleet of devices retrying
+// against a struggling operator does not synchronize on the same cadence.
+func jitter(wait time.Duration) time.Duration {
+	if wait <= 0 {
+		return 0
+	}
+
+	spread := wait / 5
+	if spread <= 0 {
+		return wait
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(spread)))
+	if err != nil {
+		return wait
+	}
+
+	return wait - spread/2 + time.Duration(n.Int64())
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		name string
+		resp *http.Response
+		err  error
+		want bool
+	}{
+		{name: "network error", resp: nil, err: errors.New("dial tcp: timeout"), want: true},
+		{name: "nil response nil error", resp: nil, err: nil, want: false},
+		{name: "429 too many requests", resp: &http.Response{StatusCode: http.StatusTooManyRequests}, want: true},
+		{name: "500 internal server error", resp: &http.Response{StatusCode: http.StatusInternalServerError}, want: true},
+		{name: "503 service unavailable", resp: &http.Response{StatusCode: http.StatusServiceUnavailable}, want: true},
+		{name: "404 not found is terminal", resp: &http.Response{StatusCode: http.StatusNotFound}, want: false},
+		{name: "400 bad request is terminal", resp: &http.Response{StatusCode: http.StatusBadRequest}, want: false},
+		{name: "200 ok is terminal", resp: &http.Response{StatusCode: http.StatusOK}, want: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isRetryable(tc.resp, tc.err); got != tc.want {
+				t.Errorf("isRetryable(%+v, %v) = %v, want %v", tc.resp, tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNextSleep(t *testing.T) {
+	cases := []struct {
+		name     string
+		current  time.Duration
+		maxSleep time.Duration
+		want     time.Duration
+	}{
+		{name: "doubles below cap", current: time.Second, maxSleep: 10 * time.Second, want: 2 * time.Second},
+		{name: "clamps to cap", current: 8 * time.Second, maxSleep: 10 * time.Second, want: 10 * time.Second},
+		{name: "no cap doubles unbounded", current: time.Minute, maxSleep: 0, want: 2 * time.Minute},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := nextSleep(tc.current, tc.maxSleep); got != tc.want {
+				t.Errorf("nextSleep(%v, %v) = %v, want %v", tc.current, tc.maxSleep, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestJitterStaysWithinSpread(t *testing.T) {
+	wait := 10 * time.Second
+	spread := wait / 5
+
+	for i := 0; i < 50; i++ {
+		got := jitter(wait)
+		if got < wait-spread/2 || got >= wait-spread/2+spread {
+			t.Fatalf("jitter(%v) = %v, want within [%v, %v)", wait, got, wait-spread/2, wait-spread/2+spread)
+		}
+	}
+}
+
+func TestJitterZeroWait(t *testing.T) {
+	if got := jitter(0); got != 0 {
+		t.Errorf("jitter(0) = %v, want 0", got)
+	}
+}
@@ -0,0 +1,161 @@
+// Package edge is the device's control loop against the flotta operator: it
+// polls for configuration updates and keeps each workload's log stream
+// subscribed or torn down according to the operator's LogTargets list.
+package edge
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	httpClient "github.com/tupyy/device-worker-ng/internal/client/http"
+	"github.com/tupyy/device-worker-ng/internal/configuration"
+	"github.com/tupyy/device-worker-ng/internal/entities"
+	"github.com/tupyy/device-worker-ng/internal/logbroker"
+	"github.com/tupyy/device-worker-ng/internal/notifier"
+	"go.uber.org/zap"
+)
+
+// pollInterval is how often the controller checks for a new LogTargets list.
+const pollInterval = 30 * time.Second
+
+// Controller is the edge-facing control loop.
+type Controller struct {
+	client      *httpClient.Client
+	confManager *configuration.Manager
+	logBroker   *logbroker.Broker
+	deviceID    string
+
+	mu                  sync.Mutex
+	subscriptions       map[string]*httpClient.LogSubscription
+	notifier            *notifier.Manager
+	messageBusPublisher notifier.MessageBusPublisher
+
+	cancel context.CancelFunc
+}
+
+// New builds a Controller. It is not started until Start is called.
+func New(client *httpClient.Client, confManager *configuration.Manager, logBroker *logbroker.Broker, deviceID string) *Controller {
+	return &Controller{
+		client:        client,
+		confManager:   confManager,
+		logBroker:     logBroker,
+		deviceID:      deviceID,
+		subscriptions: make(map[string]*httpClient.LogSubscription),
+	}
+}
+
+// SetNotifier registers the notifier.Manager whose routing table should be
+// kept in sync with the operator's configuration. It is nil-safe to call
+// with nil, which simply stops reconciling notifier routes.
+func (c *Controller) SetNotifier(n *notifier.Manager) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.notifier = n
+}
+
+// SetMessageBusPublisher registers the publisher used to construct
+// message-bus notifier routes from the operator's configuration. It is
+// nil-safe to call with nil, which simply makes reconcileNotifierRoutes skip
+// any message-bus route the operator pushes.
+func (c *Controller) SetMessageBusPublisher(publisher notifier.MessageBusPublisher) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.messageBusPublisher = publisher
+}
+
+// Start begins polling for configuration updates in the background.
+func (c *Controller) Start(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	c.cancel = cancel
+
+	go c.run(ctx)
+}
+
+func (c *Controller) run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cfg, err := c.client.GetConfiguration(ctx, c.deviceID)
+			if err != nil {
+				zap.S().Errorw("cannot fetch configuration", "error", err)
+				continue
+			}
+			c.reconcileLogTargets(ctx, cfg.LogTargets)
+			c.reconcileNotifierRoutes(cfg.NotifierRoutes)
+		}
+	}
+}
+
+// reconcileLogTargets subscribes the http client to every workload id in
+// targets that isn't already streaming, and cancels subscriptions for ids no
+// longer listed.
+func (c *Controller) reconcileLogTargets(ctx context.Context, targets []string) {
+	wanted := make(map[string]struct{}, len(targets))
+	for _, id := range targets {
+		wanted[id] = struct{}{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id := range wanted {
+		if _, ok := c.subscriptions[id]; ok {
+			continue
+		}
+
+		sub := c.client.SubscribeLogs(ctx, c.deviceID, c.logBroker, logbroker.Selector{WorkloadID: id, Follow: true})
+		c.subscriptions[id] = sub
+		zap.S().Infow("subscribed workload to log streaming", "workload", id)
+	}
+
+	for id, sub := range c.subscriptions {
+		if _, ok := wanted[id]; ok {
+			continue
+		}
+
+		sub.Cancel()
+		delete(c.subscriptions, id)
+		zap.S().Infow("stopped log streaming for workload", "workload", id)
+	}
+}
+
+// reconcileNotifierRoutes pushes the operator's current notifier route
+// configuration onto the registered notifier.Manager, if any, so routing
+// changes take effect without restarting the worker.
+func (c *Controller) reconcileNotifierRoutes(routes []entities.NotifierRouteConfig) {
+	c.mu.Lock()
+	n := c.notifier
+	publisher := c.messageBusPublisher
+	c.mu.Unlock()
+
+	if n == nil {
+		return
+	}
+
+	n.SetRoutes(notifier.RoutesFromConfig(routes, publisher))
+}
+
+// Shutdown stops polling and tears down every active log subscription.
+func (c *Controller) Shutdown(ctx context.Context) error {
+	if c.cancel != nil {
+		c.cancel()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id, sub := range c.subscriptions {
+		sub.Cancel()
+		delete(c.subscriptions, id)
+	}
+
+	return nil
+}
@@ -0,0 +1,8 @@
+package entities
+
+// CertificateRenewalRequest carries a PEM encoded certificate signing request,
+// generated with a freshly minted key, used to ask the operator for a renewed
+// client certificate without going through a full re-enrollment.
+type CertificateRenewalRequest struct {
+	CSR []byte `json:"csr"`
+}
@@ -0,0 +1,15 @@
+package entities
+
+// DeviceConfiguration is the operator's desired state for this device.
+type DeviceConfiguration struct {
+	// LogTargets lists the workload ids whose stdout/stderr should be
+	// streamed back to the operator; the edge controller reconciles its log
+	// subscriptions against this list on every configuration update.
+	LogTargets []string
+
+	// NotifierRoutes lists where profile state transitions should be
+	// delivered; the edge controller reconciles the notifier's routing
+	// table against this list on every configuration update, so an operator
+	// can change routing without restarting the worker.
+	NotifierRoutes []NotifierRouteConfig
+}
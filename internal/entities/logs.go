@@ -0,0 +1,19 @@
+package entities
+
+import "time"
+
+// LogRecord is the wire representation of a single workload log line shipped
+// to the operator.
+type LogRecord struct {
+	WorkloadID string    `json:"workload_id"`
+	Stream     string    `json:"stream"`
+	Timestamp  time.Time `json:"timestamp"`
+	Sequence   uint64    `json:"sequence"`
+	Data       []byte    `json:"data"`
+}
+
+// LogBatch groups LogRecords into a single POST body so the client does not
+// round-trip per line.
+type LogBatch struct {
+	Records []LogRecord `json:"records"`
+}
@@ -0,0 +1,46 @@
+package entities
+
+// NotifierBackendType identifies which channel a NotifierRouteConfig
+// delivers notifications through.
+type NotifierBackendType string
+
+const (
+	NotifierBackendWebhook    NotifierBackendType = "webhook"
+	NotifierBackendSMTP       NotifierBackendType = "smtp"
+	NotifierBackendMessageBus NotifierBackendType = "message-bus"
+)
+
+// NotifierWebhookConfig configures a webhook notification backend.
+type NotifierWebhookConfig struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret,omitempty"`
+}
+
+// NotifierSMTPConfig configures an SMTP notification backend.
+type NotifierSMTPConfig struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+}
+
+// NotifierMessageBusConfig configures a message-bus (Kafka/MQTT)
+// notification backend. The broker connection itself is established once,
+// outside this config, by whatever concrete publisher the process wires up;
+// Topic is all a route needs to target it.
+type NotifierMessageBusConfig struct {
+	Topic string `json:"topic"`
+}
+
+// NotifierRouteConfig is a single operator-configured notification route:
+// which backend to deliver through, and which profiles it applies to (every
+// profile, if Profiles is empty).
+type NotifierRouteConfig struct {
+	Backend    NotifierBackendType      `json:"backend"`
+	Profiles   []string                 `json:"profiles,omitempty"`
+	Webhook    NotifierWebhookConfig    `json:"webhook,omitempty"`
+	SMTP       NotifierSMTPConfig       `json:"smtp,omitempty"`
+	MessageBus NotifierMessageBusConfig `json:"messageBus,omitempty"`
+}
@@ -0,0 +1,10 @@
+package entities
+
+// ProfileState is a single profile's state as produced by the profile
+// evaluator: the label it evaluated to plus the numeric inputs behind that
+// decision, so consumers (e.g. the notifier) can report more than just the
+// state name.
+type ProfileState struct {
+	State  string
+	Values map[string]float64
+}
@@ -0,0 +1,187 @@
+// Package executor runs workloads as local processes and ships their
+// stdout/stderr to the log broker so subscribers (e.g. the edge controller)
+// can stream them to the operator.
+package executor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/tupyy/device-worker-ng/internal/logbroker"
+	"github.com/tupyy/device-worker-ng/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// Workload states reported by WorkloadStatus.
+const (
+	StateRunning = "running"
+	StateStopped = "stopped"
+	StateFailed  = "failed"
+)
+
+// WorkloadStatus is a point-in-time view of a single workload.
+type WorkloadStatus struct {
+	ID    string
+	State string
+}
+
+type workload struct {
+	id     string
+	cancel context.CancelFunc
+	state  string
+}
+
+// Executor runs workloads and publishes their output through a
+// logbroker.Publisher obtained from the log broker.
+type Executor struct {
+	logBroker *logbroker.Broker
+	metrics   *metrics.Registry
+
+	mu        sync.Mutex
+	workloads map[string]*workload
+}
+
+// New creates an Executor publishing workload output to logBroker. registry
+// may be nil, in which case no metrics are recorded.
+func New(logBroker *logbroker.Broker, registry *metrics.Registry) (*Executor, error) {
+	if logBroker == nil {
+		return nil, fmt.Errorf("log broker is missing")
+	}
+
+	return &Executor{
+		logBroker: logBroker,
+		metrics:   registry,
+		workloads: make(map[string]*workload),
+	}, nil
+}
+
+// Start runs command as workload id in the background. Its stdout/stderr are
+// streamed to the log broker under id until it exits or Stop is called.
+func (e *Executor) Start(ctx context.Context, id string, command string, args ...string) error {
+	start := time.Now()
+
+	cmd := exec.Command(command, args...)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		e.observe("start", "error", start)
+		return fmt.Errorf("cannot attach stdout for workload %s: %w", id, err)
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		e.observe("start", "error", start)
+		return fmt.Errorf("cannot attach stderr for workload %s: %w", id, err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		e.observe("start", "error", start)
+		return fmt.Errorf("cannot start workload %s: %w", id, err)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+
+	e.mu.Lock()
+	e.workloads[id] = &workload{id: id, cancel: cancel, state: StateRunning}
+	e.mu.Unlock()
+
+	publisher := e.logBroker.NewPublisher(id)
+	go streamOutput(publisher, logbroker.Stdout, stdout)
+	go streamOutput(publisher, logbroker.Stderr, stderr)
+
+	go e.wait(runCtx, id, cmd)
+
+	e.observe("start", "ok", start)
+	return nil
+}
+
+// streamOutput copies r line by line into publisher as stream records, until
+// r is closed (the workload exited or was killed).
+func streamOutput(publisher *logbroker.Publisher, stream logbroker.Stream, r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// scanner.Bytes() is a view into a buffer reused by the next Scan, but
+		// publisher retains the record (subscriber channels, broker history)
+		// past this call, so it needs its own copy.
+		line := append([]byte(nil), scanner.Bytes()...)
+		publisher.Write(stream, line)
+	}
+}
+
+func (e *Executor) wait(ctx context.Context, id string, cmd *exec.Cmd) {
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case <-ctx.Done():
+		if cmd.Process != nil {
+			_ = cmd.Process.Kill()
+		}
+		<-done
+		e.setState(id, StateStopped)
+	case err := <-done:
+		if err != nil {
+			zap.S().Errorw("workload exited with error", "workload", id, "error", err)
+			e.setState(id, StateFailed)
+		} else {
+			e.setState(id, StateStopped)
+		}
+	}
+}
+
+func (e *Executor) setState(id, state string) {
+	e.mu.Lock()
+	if w, ok := e.workloads[id]; ok {
+		w.state = state
+	}
+	e.mu.Unlock()
+}
+
+// Stop terminates workload id.
+func (e *Executor) Stop(id string) error {
+	start := time.Now()
+
+	e.mu.Lock()
+	w, ok := e.workloads[id]
+	e.mu.Unlock()
+
+	if !ok {
+		e.observe("stop", "error", start)
+		return fmt.Errorf("workload %s not found", id)
+	}
+
+	w.cancel()
+	e.observe("stop", "ok", start)
+	return nil
+}
+
+// Workloads returns a snapshot of every known workload's current state.
+func (e *Executor) Workloads() []WorkloadStatus {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	statuses := make([]WorkloadStatus, 0, len(e.workloads))
+	counts := make(map[string]int, len(e.workloads))
+	for _, w := range e.workloads {
+		statuses = append(statuses, WorkloadStatus{ID: w.id, State: w.state})
+		counts[w.state]++
+	}
+
+	if e.metrics != nil {
+		e.metrics.SetWorkloadsByState(counts)
+	}
+
+	return statuses
+}
+
+func (e *Executor) observe(operation, result string, start time.Time) {
+	if e.metrics == nil {
+		return
+	}
+	e.metrics.ObserveExecutorOperation(operation, result, time.Since(start))
+}
@@ -0,0 +1,201 @@
+// Package logbroker multiplexes workload log records between the executor,
+// which produces them, and any number of subscribers (today: the cluster
+// http client shipping them to the operator) that want to follow a subset of
+// workloads.
+package logbroker
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Stream identifies which standard stream a Record came from.
+type Stream string
+
+const (
+	Stdout Stream = "stdout"
+	Stderr Stream = "stderr"
+)
+
+// defaultBufferSize is the number of records buffered per subscriber before
+// the oldest one is dropped to make room for new ones.
+const defaultBufferSize = 256
+
+// defaultHistorySize is how many of the most recent records the Broker keeps
+// per workload, so a Selector can catch up on what it missed before it
+// subscribed.
+const defaultHistorySize = 256
+
+// Record is a single log line produced by a running workload.
+type Record struct {
+	WorkloadID string
+	Stream     Stream
+	Timestamp  time.Time
+	Sequence   uint64
+	Data       []byte
+}
+
+// Selector scopes a subscription to a workload (or all workloads, when empty)
+// and whether it should keep following new records after catching up. When
+// Follow is false, the subscription receives the matching records currently
+// held in the Broker's history and is then closed; it never sees records
+// published afterwards.
+type Selector struct {
+	WorkloadID string
+	Follow     bool
+}
+
+func (s Selector) matches(record Record) bool {
+	return s.WorkloadID == "" || s.WorkloadID == record.WorkloadID
+}
+
+// Broker fans out Records published by the executor to every subscriber whose
+// Selector matches.
+type Broker struct {
+	mu      sync.RWMutex
+	subs    map[string]*subscription
+	history map[string][]Record
+
+	nextID uint64
+}
+
+type subscription struct {
+	id       string
+	selector Selector
+	ch       chan Record
+	dropped  uint64
+}
+
+// New creates an empty Broker.
+func New() *Broker {
+	return &Broker{
+		subs:    make(map[string]*subscription),
+		history: make(map[string][]Record),
+	}
+}
+
+// Subscribe registers a new subscriber matching selector and returns its
+// subscription id, the channel records are delivered on, and a cancel
+// function which unregisters it and closes the channel. The channel is first
+// seeded with whatever history matches selector; if selector.Follow is
+// false, the subscription stops there, the channel is closed, and it is
+// never registered to receive records published afterwards.
+func (b *Broker) Subscribe(selector Selector) (id string, records <-chan Record, cancel func()) {
+	b.mu.Lock()
+
+	ch := make(chan Record, defaultBufferSize)
+	seed := b.matchingHistoryLocked(selector)
+	if len(seed) > defaultBufferSize {
+		// history outgrew the subscriber buffer; drop the oldest rather
+		// than block Subscribe. Only a selector spanning several workloads
+		// gets here, and matchingHistoryLocked then returns a fresh slice.
+		sort.SliceStable(seed, func(i, j int) bool { return seed[i].Timestamp.Before(seed[j].Timestamp) })
+		seed = seed[len(seed)-defaultBufferSize:]
+	}
+	for _, record := range seed {
+		ch <- record
+	}
+
+	if !selector.Follow {
+		b.mu.Unlock()
+		close(ch)
+		return "", ch, func() {}
+	}
+
+	b.nextID++
+	id = fmt.Sprintf("sub-%d", b.nextID)
+	sub := &subscription{
+		id:       id,
+		selector: selector,
+		ch:       ch,
+	}
+	b.subs[id] = sub
+	b.mu.Unlock()
+
+	cancel = func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if _, ok := b.subs[id]; !ok {
+			return
+		}
+		delete(b.subs, id)
+		close(sub.ch)
+	}
+
+	return id, sub.ch, cancel
+}
+
+// matchingHistoryLocked returns the buffered records matching selector.
+// Callers must hold b.mu.
+func (b *Broker) matchingHistoryLocked(selector Selector) []Record {
+	if selector.WorkloadID != "" {
+		return b.history[selector.WorkloadID]
+	}
+
+	var all []Record
+	for _, records := range b.history {
+		all = append(all, records...)
+	}
+
+	return all
+}
+
+// Publish fans record out to every subscriber whose selector matches and
+// appends it to the per-workload history used to catch up future
+// subscribers. A subscriber that cannot keep up has its oldest buffered
+// record dropped to make room, so a slow consumer never blocks the executor.
+func (b *Broker) Publish(record Record) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	history := append(b.history[record.WorkloadID], record)
+	if len(history) > defaultHistorySize {
+		history = history[len(history)-defaultHistorySize:]
+	}
+	b.history[record.WorkloadID] = history
+
+	for _, sub := range b.subs {
+		if !sub.selector.matches(record) {
+			continue
+		}
+
+		select {
+		case sub.ch <- record:
+		default:
+			select {
+			case <-sub.ch:
+				atomic.AddUint64(&sub.dropped, 1)
+				zap.S().Warnw("log subscriber buffer full, dropping oldest record", "subscription", sub.id, "workload", record.WorkloadID)
+			default:
+			}
+
+			select {
+			case sub.ch <- record:
+			default:
+				// the buffer was refilled by a concurrent publish between the
+				// drop and this send; give up on this record rather than block.
+			}
+		}
+	}
+}
+
+// DroppedCount returns how many records were dropped for the subscription id
+// because its subscriber could not keep up. The count is not exported as a
+// metric.
+func (b *Broker) DroppedCount(id string) uint64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	sub, ok := b.subs[id]
+	if !ok {
+		return 0
+	}
+
+	return atomic.LoadUint64(&sub.dropped)
+}
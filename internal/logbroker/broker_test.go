@@ -0,0 +1,110 @@
+package logbroker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPublishDropsOldestWhenSubscriberBufferFull(t *testing.T) {
+	b := New()
+	_, records, cancel := b.Subscribe(Selector{WorkloadID: "wl", Follow: true})
+	defer cancel()
+
+	total := defaultBufferSize + 5
+	for i := 0; i < total; i++ {
+		b.Publish(Record{WorkloadID: "wl", Sequence: uint64(i)})
+	}
+
+	var id string
+	for subID := range b.subs {
+		id = subID
+	}
+
+	if got := b.DroppedCount(id); got != 5 {
+		t.Errorf("DroppedCount() = %d, want 5", got)
+	}
+
+	// the channel should now hold the most recent defaultBufferSize records,
+	// starting at sequence 5 (the first 5 published were dropped to make room).
+	first := <-records
+	if first.Sequence != 5 {
+		t.Errorf("oldest record still buffered has Sequence = %d, want 5", first.Sequence)
+	}
+}
+
+func TestSubscribeSeedsFromHistory(t *testing.T) {
+	b := New()
+	b.Publish(Record{WorkloadID: "wl", Sequence: 1})
+	b.Publish(Record{WorkloadID: "wl", Sequence: 2})
+	b.Publish(Record{WorkloadID: "other", Sequence: 1})
+
+	_, records, cancel := b.Subscribe(Selector{WorkloadID: "wl", Follow: true})
+	defer cancel()
+
+	select {
+	case r := <-records:
+		if r.Sequence != 1 {
+			t.Errorf("first seeded record Sequence = %d, want 1", r.Sequence)
+		}
+	default:
+		t.Fatal("expected subscription channel to be seeded with matching history")
+	}
+
+	select {
+	case r := <-records:
+		if r.Sequence != 2 {
+			t.Errorf("second seeded record Sequence = %d, want 2", r.Sequence)
+		}
+	default:
+		t.Fatal("expected a second seeded record")
+	}
+}
+
+func TestSubscribeWithoutFollowClosesAfterHistory(t *testing.T) {
+	b := New()
+	b.Publish(Record{WorkloadID: "wl", Sequence: 1})
+
+	_, records, cancel := b.Subscribe(Selector{WorkloadID: "wl", Follow: false})
+	defer cancel()
+
+	select {
+	case r, ok := <-records:
+		if !ok {
+			t.Fatal("channel closed before delivering seeded history")
+		}
+		if r.Sequence != 1 {
+			t.Errorf("seeded record Sequence = %d, want 1", r.Sequence)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for seeded history")
+	}
+
+	select {
+	case _, ok := <-records:
+		if ok {
+			t.Fatal("non-following subscription should not receive records published after catch-up")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for channel to close")
+	}
+
+	b.Publish(Record{WorkloadID: "wl", Sequence: 2})
+}
+
+func TestHistoryIsBoundedBydefaultHistorySize(t *testing.T) {
+	b := New()
+	for i := 0; i < defaultHistorySize+10; i++ {
+		b.Publish(Record{WorkloadID: "wl", Sequence: uint64(i)})
+	}
+
+	b.mu.RLock()
+	history := b.history["wl"]
+	b.mu.RUnlock()
+
+	if len(history) != defaultHistorySize {
+		t.Fatalf("history length = %d, want %d", len(history), defaultHistorySize)
+	}
+	if history[0].Sequence != 10 {
+		t.Errorf("oldest retained history record Sequence = %d, want 10", history[0].Sequence)
+	}
+}
@@ -0,0 +1,37 @@
+package logbroker
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Publisher is handed to a running workload so it can ship its stdout/stderr
+// back through the Broker without knowing anything about subscribers or
+// transport. The executor creates one per workload via Broker.NewPublisher.
+// A Publisher has no Cancel or Close: nothing is torn down when the workload
+// exits.
+type Publisher struct {
+	workloadID string
+	broker     *Broker
+	sequence   uint64
+}
+
+// NewPublisher returns a Publisher tagging every record it emits with
+// workloadID.
+func (b *Broker) NewPublisher(workloadID string) *Publisher {
+	return &Publisher{workloadID: workloadID, broker: b}
+}
+
+// Write publishes data read from stream, stamping it with the current time
+// and the next sequence number for this workload.
+func (p *Publisher) Write(stream Stream, data []byte) {
+	seq := atomic.AddUint64(&p.sequence, 1)
+
+	p.broker.Publish(Record{
+		WorkloadID: p.workloadID,
+		Stream:     stream,
+		Timestamp:  time.Now(),
+		Sequence:   seq,
+		Data:       data,
+	})
+}
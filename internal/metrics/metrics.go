@@ -0,0 +1,157 @@
+// Package metrics is the single place where device-worker-ng's Prometheus
+// collectors are registered, so business logic (scheduler, executor, http
+// client) never has to import the Prometheus client directly: it takes a
+// *Registry and calls a named method instead.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "device_worker"
+
+// Registry holds every collector device-worker-ng exposes and is the only
+// type in this package business logic needs to know about.
+type Registry struct {
+	workloadsByState *prometheus.GaugeVec
+	schedulerQueue   prometheus.Gauge
+
+	executorStarts   *prometheus.CounterVec
+	executorDuration *prometheus.HistogramVec
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	certificateExpiry prometheus.Gauge
+	profileEvalLatency prometheus.Histogram
+	sampleRate         prometheus.Gauge
+
+	registry *prometheus.Registry
+}
+
+// New creates a Registry with every collector registered and ready to use.
+func New() *Registry {
+	reg := prometheus.NewRegistry()
+
+	r := &Registry{
+		workloadsByState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "workloads",
+			Help:      "Number of workloads currently in each state.",
+		}, []string{"state"}),
+		schedulerQueue: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "scheduler_queue_depth",
+			Help:      "Number of workload updates waiting to be scheduled.",
+		}),
+		executorStarts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "executor_workload_total",
+			Help:      "Number of workload start/stop operations performed by the executor.",
+		}, []string{"operation", "result"}),
+		executorDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "executor_workload_duration_seconds",
+			Help:      "Duration of executor start/stop operations.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation"}),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_client_requests_total",
+			Help:      "Number of requests the http client made to the operator, by action and status.",
+		}, []string{"action", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_client_request_duration_seconds",
+			Help:      "Latency of requests the http client made to the operator, by action.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"action"}),
+		certificateExpiry: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "certificate_expiry_seconds",
+			Help:      "Seconds remaining until the client certificate expires.",
+		}),
+		profileEvalLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "profile_evaluation_duration_seconds",
+			Help:      "Time between consecutive profile manager evaluation results.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		sampleRate: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "metrics_sample_rate",
+			Help:      "Fraction of samples this process reports, for fleets that downsample per-device metrics.",
+		}),
+		registry: reg,
+	}
+
+	reg.MustRegister(
+		r.workloadsByState,
+		r.schedulerQueue,
+		r.executorStarts,
+		r.executorDuration,
+		r.httpRequests,
+		r.httpDuration,
+		r.certificateExpiry,
+		r.profileEvalLatency,
+		r.sampleRate,
+	)
+
+	return r
+}
+
+// Handler returns the /metrics HTTP handler for this registry.
+func (r *Registry) Handler() http.Handler {
+	return promhttp.HandlerFor(r.registry, promhttp.HandlerOpts{})
+}
+
+// SetWorkloadsByState replaces the workload-state gauge with counts, keyed by
+// state name (e.g. "running", "stopped", "failed").
+func (r *Registry) SetWorkloadsByState(counts map[string]int) {
+	r.workloadsByState.Reset()
+	for state, count := range counts {
+		r.workloadsByState.WithLabelValues(state).Set(float64(count))
+	}
+}
+
+// SetSchedulerQueueDepth records how many workload updates are waiting to be
+// scheduled.
+func (r *Registry) SetSchedulerQueueDepth(depth int) {
+	r.schedulerQueue.Set(float64(depth))
+}
+
+// ObserveExecutorOperation records a single start/stop operation ("start" or
+// "stop") with its outcome ("ok" or "error") and how long it took.
+func (r *Registry) ObserveExecutorOperation(operation, result string, duration time.Duration) {
+	r.executorStarts.WithLabelValues(operation, result).Inc()
+	r.executorDuration.WithLabelValues(operation).Observe(duration.Seconds())
+}
+
+// ObserveHTTPRequest records a single http client request partitioned by
+// requestBuilder's action type and the response status (or "error" if the
+// round trip failed outright).
+func (r *Registry) ObserveHTTPRequest(action, status string, duration time.Duration) {
+	r.httpRequests.WithLabelValues(action, status).Inc()
+	r.httpDuration.WithLabelValues(action).Observe(duration.Seconds())
+}
+
+// SetCertificateExpiry records how many seconds remain until the current
+// client certificate expires.
+func (r *Registry) SetCertificateExpiry(remaining time.Duration) {
+	r.certificateExpiry.Set(remaining.Seconds())
+}
+
+// ObserveProfileEvaluation records the time between two consecutive profile
+// manager evaluation results.
+func (r *Registry) ObserveProfileEvaluation(duration time.Duration) {
+	r.profileEvalLatency.Observe(duration.Seconds())
+}
+
+// SetSampleRate records the fraction of samples this process reports.
+func (r *Registry) SetSampleRate(rate float64) {
+	r.sampleRate.Set(rate)
+}
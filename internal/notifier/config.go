@@ -0,0 +1,47 @@
+package notifier
+
+import (
+	"github.com/tupyy/device-worker-ng/internal/entities"
+	"go.uber.org/zap"
+)
+
+// RoutesFromConfig translates the operator-pushed route configuration into
+// concrete Routes, skipping any entry with an unrecognised backend type so
+// one bad entry doesn't take down every other route. messageBusPublisher is
+// the concrete Kafka/MQTT client adapted to MessageBusPublisher by the
+// caller; it may be nil, in which case message-bus routes are skipped like
+// an unrecognised backend, since there is nothing to publish through.
+func RoutesFromConfig(configs []entities.NotifierRouteConfig, messageBusPublisher MessageBusPublisher) []Route {
+	routes := make([]Route, 0, len(configs))
+
+	for _, cfg := range configs {
+		var backend Backend
+
+		switch cfg.Backend {
+		case entities.NotifierBackendWebhook:
+			backend = NewWebhookBackend(WebhookConfig{URL: cfg.Webhook.URL, Secret: cfg.Webhook.Secret})
+		case entities.NotifierBackendSMTP:
+			backend = NewSMTPBackend(SMTPConfig{
+				Host:     cfg.SMTP.Host,
+				Port:     cfg.SMTP.Port,
+				From:     cfg.SMTP.From,
+				To:       cfg.SMTP.To,
+				Username: cfg.SMTP.Username,
+				Password: cfg.SMTP.Password,
+			})
+		case entities.NotifierBackendMessageBus:
+			if messageBusPublisher == nil {
+				zap.S().Warnw("ignoring message-bus notifier route, no publisher configured", "topic", cfg.MessageBus.Topic)
+				continue
+			}
+			backend = NewMessageBusBackend(cfg.MessageBus.Topic, messageBusPublisher)
+		default:
+			zap.S().Warnw("ignoring notifier route with unknown backend type", "backend", cfg.Backend)
+			continue
+		}
+
+		routes = append(routes, Route{Backend: backend, Profiles: cfg.Profiles})
+	}
+
+	return routes
+}
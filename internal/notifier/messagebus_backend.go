@@ -0,0 +1,46 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// MessageBusPublisher is the minimal shape needed from a Kafka or MQTT
+// client to publish a notification. Concrete clients (e.g. a Kafka producer
+// or an MQTT client) are adapted to this interface at the call site so this
+// package does not depend on a specific broker library.
+type MessageBusPublisher interface {
+	Publish(ctx context.Context, topic string, payload []byte) error
+}
+
+// MessageBusBackend publishes notifications as JSON to a fixed topic on a
+// Kafka or MQTT broker.
+type MessageBusBackend struct {
+	topic     string
+	publisher MessageBusPublisher
+}
+
+// NewMessageBusBackend creates a Backend publishing events to topic through
+// publisher.
+func NewMessageBusBackend(topic string, publisher MessageBusPublisher) *MessageBusBackend {
+	return &MessageBusBackend{topic: topic, publisher: publisher}
+}
+
+// Name identifies this backend instance by its topic, so two message-bus
+// routes are never confused with each other when the outbox replays a
+// failed delivery (see Manager.redeliver).
+func (b *MessageBusBackend) Name() string { return fmt.Sprintf("message-bus:%s", b.topic) }
+
+func (b *MessageBusBackend) Deliver(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("cannot encode notification payload: %w", err)
+	}
+
+	if err := b.publisher.Publish(ctx, b.topic, payload); err != nil {
+		return fmt.Errorf("cannot publish notification: %w", err)
+	}
+
+	return nil
+}
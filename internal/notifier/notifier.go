@@ -0,0 +1,380 @@
+// Package notifier alerts operators when a device's profile flips state
+// (e.g. battery=low, temperature=critical), routing the event to one or more
+// pluggable backends (SMTP, webhook, message bus).
+package notifier
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/tupyy/device-worker-ng/internal/entities"
+	"go.uber.org/zap"
+)
+
+// Event is a single profile state transition.
+type Event struct {
+	// ID uniquely identifies this transition so the outbox can track whether
+	// it was delivered without relying on struct equality (Values is a map).
+	ID string
+
+	DeviceID  string
+	Profile   string
+	Previous  string
+	Current   string
+	Values    map[string]float64
+	Timestamp time.Time
+}
+
+// Backend delivers an Event through one channel (SMTP, webhook, message bus).
+type Backend interface {
+	Name() string
+	Deliver(ctx context.Context, event Event) error
+}
+
+// Route binds a backend to the profiles it should be notified about. An empty
+// Profiles list matches every profile.
+type Route struct {
+	Backend  Backend
+	Profiles []string
+}
+
+func (r Route) matches(profileName string) bool {
+	if len(r.Profiles) == 0 {
+		return true
+	}
+
+	for _, name := range r.Profiles {
+		if name == profileName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Manager subscribes to a profile manager's OutputCh, deduplicates rapid
+// flapping with a hold-down timer and delivers the resulting events to every
+// matching Route, retrying failed deliveries through an Outbox so they
+// survive a worker restart.
+type Manager struct {
+	holdDown time.Duration
+	outbox   *Outbox
+	deviceID string
+
+	mu     sync.Mutex
+	routes []Route
+
+	// stableState holds the last state each profile was confirmed in, i.e.
+	// the state it was in before any in-flight hold-down window started.
+	// It is only updated once a pending transition actually fires.
+	stableState map[string]string
+
+	// pending holds the in-flight hold-down timer for a profile currently
+	// mid-transition, if any.
+	pending map[string]*pendingTransition
+
+	cancel context.CancelFunc
+}
+
+// pendingTransition tracks a profile transition waiting out its hold-down
+// window. from is the profile's last confirmed (stable) state, captured once
+// when the window opened; event is recomputed every time the profile flaps
+// to a new state before the window elapses, so it always reflects from ->
+// the latest observed state rather than the intermediate ones.
+type pendingTransition struct {
+	timer *time.Timer
+	from  string
+	event Event
+}
+
+// New creates a Manager delivering events to routes. holdDown is the minimum
+// time a new profile state must persist before a notification is sent; zero
+// disables deduplication. outbox may be nil, in which case a failed delivery
+// is dropped and never retried.
+func New(deviceID string, routes []Route, holdDown time.Duration, outbox *Outbox) *Manager {
+	return &Manager{
+		deviceID:    deviceID,
+		routes:      routes,
+		holdDown:    holdDown,
+		outbox:      outbox,
+		stableState: make(map[string]string),
+		pending:     make(map[string]*pendingTransition),
+	}
+}
+
+const (
+	// redeliverAttemptTimeout bounds a single outbox replay attempt, so a
+	// backend that is slow to respond (an unreachable webhook, a stalled
+	// SMTP relay) cannot hang the replay loop on it indefinitely.
+	redeliverAttemptTimeout = 30 * time.Second
+
+	// defaultReplayInterval is how often the outbox is replayed while it is
+	// draining cleanly (empty, or every entry delivered).
+	defaultReplayInterval = 30 * time.Second
+
+	// maxReplayInterval caps the backoff applied between replay passes that
+	// still leave entries undelivered.
+	maxReplayInterval = 30 * time.Minute
+
+	// replayJitterWindow spreads replay passes across a fleet of devices so
+	// they do not all hammer a recovering backend at once.
+	replayJitterWindow = 10 * time.Second
+)
+
+// Start subscribes to updates and begins delivering notifications. It also
+// starts a background loop which periodically replays anything left in the
+// outbox (from this run or a previous one), backing off while deliveries
+// keep failing, so a slow or unreachable backend cannot hold up worker
+// startup or require a restart to retry.
+func (m *Manager) Start(ctx context.Context, updates <-chan map[string]entities.ProfileState) {
+	runCtx, cancel := context.WithCancel(ctx)
+	m.cancel = cancel
+
+	if m.outbox != nil {
+		go m.replayLoop(runCtx)
+	}
+
+	go m.run(runCtx, updates)
+}
+
+// replayLoop periodically drains the outbox, applying the same exponential
+// backoff/jitter shape used elsewhere (see certificate.renewalLoop and
+// client/http.retryRoundTripper) whenever a pass leaves entries undelivered.
+func (m *Manager) replayLoop(ctx context.Context) {
+	interval := defaultReplayInterval
+
+	for {
+		if m.replayOutboxOnce(ctx) {
+			interval = defaultReplayInterval
+		} else {
+			interval = nextReplayInterval(interval)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval + jitter(replayJitterWindow)):
+		}
+	}
+}
+
+// replayOutboxOnce retries every entry currently in the outbox and reports
+// whether all of them were delivered (or dropped because their backend is no
+// longer configured).
+func (m *Manager) replayOutboxOnce(ctx context.Context) bool {
+	clean := true
+
+	for _, entry := range m.outbox.Pending() {
+		attemptCtx, cancel := context.WithTimeout(ctx, redeliverAttemptTimeout)
+		err := m.redeliver(attemptCtx, entry)
+		cancel()
+
+		if ctx.Err() != nil {
+			return clean
+		}
+		if err != nil {
+			clean = false
+		}
+	}
+
+	return clean
+}
+
+func nextReplayInterval(current time.Duration) time.Duration {
+	next := current * 2
+	if next > maxReplayInterval {
+		return maxReplayInterval
+	}
+	return next
+}
+
+func jitter(window time.Duration) time.Duration {
+	if window <= 0 {
+		return 0
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(window)))
+	if err != nil {
+		return 0
+	}
+
+	return time.Duration(n.Int64())
+}
+
+// Shutdown stops delivering notifications. Events still pending in the
+// hold-down window are dropped; anything already in the outbox remains there
+// for the next Start to retry.
+func (m *Manager) Shutdown() {
+	if m.cancel != nil {
+		m.cancel()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for profileName, p := range m.pending {
+		p.timer.Stop()
+		delete(m.pending, profileName)
+	}
+}
+
+// SetRoutes atomically replaces the routing table, so an operator-pushed
+// configuration update can change where notifications are delivered
+// without restarting the worker.
+func (m *Manager) SetRoutes(routes []Route) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.routes = routes
+}
+
+func (m *Manager) routesSnapshot() []Route {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.routes
+}
+
+func (m *Manager) run(ctx context.Context, updates <-chan map[string]entities.ProfileState) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case states, ok := <-updates:
+			if !ok {
+				return
+			}
+
+			for profileName, state := range states {
+				m.handleTransition(ctx, profileName, state)
+			}
+		}
+	}
+}
+
+// handleTransition processes a newly observed profile state. While a prior
+// transition for the same profile is still inside its hold-down window, a
+// flap back to that window's original stable state cancels it outright
+// (suppressing the whole thing) instead of recomputing it as yet another
+// transition, so a profile that bounces B->C->B within the window never
+// produces a C->B notification for a state it never really left.
+func (m *Manager) handleTransition(ctx context.Context, profileName string, newState entities.ProfileState) {
+	m.mu.Lock()
+
+	if p, ok := m.pending[profileName]; ok {
+		if newState.State == p.from {
+			p.timer.Stop()
+			delete(m.pending, profileName)
+			m.mu.Unlock()
+			return
+		}
+
+		p.timer.Stop()
+		p.event = m.newEvent(profileName, p.from, newState)
+		p.timer = time.AfterFunc(m.holdDown, func() { m.firePending(ctx, profileName) })
+		m.mu.Unlock()
+		return
+	}
+
+	stable, known := m.stableState[profileName]
+	if known && stable == newState.State {
+		m.mu.Unlock()
+		return
+	}
+
+	event := m.newEvent(profileName, stable, newState)
+
+	if m.holdDown <= 0 {
+		m.stableState[profileName] = newState.State
+		m.mu.Unlock()
+		m.deliver(ctx, event)
+		return
+	}
+
+	timer := time.AfterFunc(m.holdDown, func() { m.firePending(ctx, profileName) })
+	m.pending[profileName] = &pendingTransition{timer: timer, from: stable, event: event}
+	m.mu.Unlock()
+}
+
+// firePending delivers the pending transition for profileName, if it is
+// still pending once the hold-down timer fires, and confirms its target
+// state as the new stable state.
+func (m *Manager) firePending(ctx context.Context, profileName string) {
+	m.mu.Lock()
+	p, ok := m.pending[profileName]
+	if !ok {
+		m.mu.Unlock()
+		return
+	}
+	delete(m.pending, profileName)
+	m.stableState[profileName] = p.event.Current
+	event := p.event
+	m.mu.Unlock()
+
+	m.deliver(ctx, event)
+}
+
+func (m *Manager) newEvent(profileName, previous string, newState entities.ProfileState) Event {
+	now := time.Now()
+	return Event{
+		ID:        fmt.Sprintf("%s/%s/%d", m.deviceID, profileName, now.UnixNano()),
+		DeviceID:  m.deviceID,
+		Profile:   profileName,
+		Previous:  previous,
+		Current:   newState.State,
+		Values:    newState.Values,
+		Timestamp: now,
+	}
+}
+
+func (m *Manager) deliver(ctx context.Context, event Event) {
+	for _, route := range m.routesSnapshot() {
+		if !route.matches(event.Profile) {
+			continue
+		}
+
+		m.deliverTo(ctx, route.Backend, event)
+	}
+}
+
+// redeliver retries a single outbox entry against the backend it originally
+// failed to reach, reporting whether it was delivered.
+func (m *Manager) redeliver(ctx context.Context, entry OutboxEntry) error {
+	for _, route := range m.routesSnapshot() {
+		if route.Backend.Name() != entry.Backend {
+			continue
+		}
+
+		return m.deliverTo(ctx, route.Backend, entry.Event)
+	}
+
+	// the backend that originally failed is no longer configured; drop it
+	// rather than retrying forever.
+	m.outbox.Remove(entry)
+	return nil
+}
+
+func (m *Manager) deliverTo(ctx context.Context, backend Backend, event Event) error {
+	if err := backend.Deliver(ctx, event); err != nil {
+		zap.S().Errorw("cannot deliver notification, queuing to outbox",
+			"backend", backend.Name(), "profile", event.Profile, "error", err)
+
+		if m.outbox != nil {
+			if enqueueErr := m.outbox.Enqueue(OutboxEntry{Backend: backend.Name(), Event: event}); enqueueErr != nil {
+				zap.S().Errorw("cannot persist notification to outbox", "error", enqueueErr)
+			}
+		}
+
+		return err
+	}
+
+	if m.outbox != nil {
+		m.outbox.Remove(OutboxEntry{Backend: backend.Name(), Event: event})
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tupyy/device-worker-ng/internal/entities"
+)
+
+var errDeliveryFailed = errors.New("delivery failed")
+
+// fakeBackend records delivered events and can be made to fail deliveries.
+type fakeBackend struct {
+	name string
+
+	mu        sync.Mutex
+	delivered []Event
+	fail      bool
+}
+
+func (b *fakeBackend) Name() string { return b.name }
+
+func (b *fakeBackend) Deliver(ctx context.Context, event Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.fail {
+		return errDeliveryFailed
+	}
+	b.delivered = append(b.delivered, event)
+	return nil
+}
+
+func (b *fakeBackend) events() []Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	out := make([]Event, len(b.delivered))
+	copy(out, b.delivered)
+	return out
+}
+
+func newTestManager(backend *fakeBackend, holdDown time.Duration) *Manager {
+	return New("device-1", []Route{{Backend: backend}}, holdDown, nil)
+}
+
+func TestHandleTransitionFlapWithinHoldDownIsSuppressed(t *testing.T) {
+	backend := &fakeBackend{name: "test"}
+	m := newTestManager(backend, 50*time.Millisecond)
+	ctx := context.Background()
+
+	m.stableState["battery"] = "B"
+
+	m.handleTransition(ctx, "battery", entities.ProfileState{State: "C"})
+	m.handleTransition(ctx, "battery", entities.ProfileState{State: "B"})
+
+	time.Sleep(100 * time.Millisecond)
+
+	if got := backend.events(); len(got) != 0 {
+		t.Fatalf("delivered events = %v, want none (B->C->B flap should be fully suppressed)", got)
+	}
+	if _, pending := m.pending["battery"]; pending {
+		t.Error("pending transition should be cleared after a flap back to the stable state")
+	}
+	if m.stableState["battery"] != "B" {
+		t.Errorf("stableState[battery] = %q, want %q", m.stableState["battery"], "B")
+	}
+}
+
+func TestHandleTransitionFiresAfterHoldDown(t *testing.T) {
+	backend := &fakeBackend{name: "test"}
+	m := newTestManager(backend, 20*time.Millisecond)
+	ctx := context.Background()
+
+	m.stableState["battery"] = "B"
+	m.handleTransition(ctx, "battery", entities.ProfileState{State: "C"})
+
+	time.Sleep(100 * time.Millisecond)
+
+	events := backend.events()
+	if len(events) != 1 {
+		t.Fatalf("delivered events = %d, want 1", len(events))
+	}
+	if events[0].Previous != "B" || events[0].Current != "C" {
+		t.Errorf("event = %+v, want Previous=B Current=C", events[0])
+	}
+
+	m.mu.Lock()
+	stable := m.stableState["battery"]
+	m.mu.Unlock()
+	if stable != "C" {
+		t.Errorf("stableState[battery] = %q, want %q after firing", stable, "C")
+	}
+}
+
+func TestHandleTransitionReflapRetargetsToLatestState(t *testing.T) {
+	backend := &fakeBackend{name: "test"}
+	m := newTestManager(backend, 40*time.Millisecond)
+	ctx := context.Background()
+
+	m.stableState["battery"] = "A"
+	m.handleTransition(ctx, "battery", entities.ProfileState{State: "B"})
+	m.handleTransition(ctx, "battery", entities.ProfileState{State: "C"})
+
+	time.Sleep(100 * time.Millisecond)
+
+	events := backend.events()
+	if len(events) != 1 {
+		t.Fatalf("delivered events = %d, want 1", len(events))
+	}
+	if events[0].Previous != "A" || events[0].Current != "C" {
+		t.Errorf("event = %+v, want Previous=A Current=C (the original stable state, not the intermediate B)", events[0])
+	}
+}
+
+func TestHandleTransitionNoHoldDownDeliversImmediately(t *testing.T) {
+	backend := &fakeBackend{name: "test"}
+	m := newTestManager(backend, 0)
+	ctx := context.Background()
+
+	m.handleTransition(ctx, "battery", entities.ProfileState{State: "C"})
+
+	if got := backend.events(); len(got) != 1 {
+		t.Fatalf("delivered events = %d, want 1 (immediate delivery with no hold-down)", len(got))
+	}
+}
+
+func TestNextReplayInterval(t *testing.T) {
+	cases := []struct {
+		current time.Duration
+		want    time.Duration
+	}{
+		{current: defaultReplayInterval, want: 2 * defaultReplayInterval},
+		{current: maxReplayInterval, want: maxReplayInterval},
+		{current: maxReplayInterval / 2, want: maxReplayInterval},
+	}
+
+	for _, tc := range cases {
+		if got := nextReplayInterval(tc.current); got != tc.want {
+			t.Errorf("nextReplayInterval(%v) = %v, want %v", tc.current, got, tc.want)
+		}
+	}
+}
+
+func TestRedeliverDropsEntryWhenBackendNoLongerConfigured(t *testing.T) {
+	dir := t.TempDir()
+	outbox, err := OpenOutbox(dir + "/outbox.jsonl")
+	if err != nil {
+		t.Fatalf("cannot open outbox: %v", err)
+	}
+
+	m := New("device-1", nil, time.Second, outbox)
+	entry := OutboxEntry{Backend: "gone", Event: Event{ID: "evt-1"}}
+	if err := outbox.Enqueue(entry); err != nil {
+		t.Fatalf("cannot enqueue: %v", err)
+	}
+
+	if err := m.redeliver(context.Background(), entry); err != nil {
+		t.Fatalf("redeliver() error = %v", err)
+	}
+
+	if got := outbox.Pending(); len(got) != 0 {
+		t.Errorf("outbox still has %d pending entries, want 0 after dropping an orphaned entry", len(got))
+	}
+}
@@ -0,0 +1,108 @@
+package notifier
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// OutboxEntry is a single notification awaiting (re)delivery to Backend.
+type OutboxEntry struct {
+	Backend string `json:"backend"`
+	Event   Event  `json:"event"`
+}
+
+// Outbox persists undelivered notifications to a newline-delimited JSON file
+// so they survive a worker restart. It is safe for concurrent use.
+type Outbox struct {
+	mu      sync.Mutex
+	path    string
+	entries []OutboxEntry
+}
+
+// OpenOutbox loads path, creating it if it does not exist yet.
+func OpenOutbox(path string) (*Outbox, error) {
+	o := &Outbox{path: path}
+
+	f, err := os.OpenFile(path, os.O_RDONLY|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open outbox: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry OutboxEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		o.entries = append(o.entries, entry)
+	}
+
+	return o, nil
+}
+
+// Pending returns a snapshot of the entries currently queued.
+func (o *Outbox) Pending() []OutboxEntry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	pending := make([]OutboxEntry, len(o.entries))
+	copy(pending, o.entries)
+	return pending
+}
+
+// Enqueue appends entry to the outbox and persists it to disk. An entry for
+// the same backend and event ID already queued is kept as is, so a failed
+// replay does not queue it twice.
+func (o *Outbox) Enqueue(entry OutboxEntry) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, e := range o.entries {
+		if e.Backend == entry.Backend && e.Event.ID == entry.Event.ID {
+			return nil
+		}
+	}
+
+	o.entries = append(o.entries, entry)
+	return o.flushLocked()
+}
+
+// Remove drops entry from the outbox, if present, and persists the change.
+func (o *Outbox) Remove(entry OutboxEntry) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for i, e := range o.entries {
+		if e.Backend == entry.Backend && e.Event.ID == entry.Event.ID {
+			o.entries = append(o.entries[:i], o.entries[i+1:]...)
+			_ = o.flushLocked()
+			return
+		}
+	}
+}
+
+func (o *Outbox) flushLocked() error {
+	tmp := o.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("cannot write outbox: %w", err)
+	}
+
+	enc := json.NewEncoder(f)
+	for _, entry := range o.entries {
+		if err := enc.Encode(entry); err != nil {
+			f.Close()
+			return fmt.Errorf("cannot encode outbox entry: %w", err)
+		}
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("cannot close outbox: %w", err)
+	}
+
+	return os.Rename(tmp, o.path)
+}
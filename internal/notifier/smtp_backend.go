@@ -0,0 +1,127 @@
+package notifier
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+// SMTPConfig configures an SMTPBackend.
+type SMTPConfig struct {
+	Host string
+	Port int
+	From string
+	To   []string
+
+	Username string
+	Password string
+}
+
+// SMTPBackend delivers notifications as plain text email.
+type SMTPBackend struct {
+	cfg SMTPConfig
+}
+
+// NewSMTPBackend creates a Backend delivering events through an SMTP relay.
+func NewSMTPBackend(cfg SMTPConfig) *SMTPBackend {
+	return &SMTPBackend{cfg: cfg}
+}
+
+// Name identifies this backend instance by its relay address and
+// recipients, so two SMTP routes are never confused with each other when
+// the outbox replays a failed delivery (see Manager.redeliver).
+func (b *SMTPBackend) Name() string {
+	return fmt.Sprintf("smtp:%s:%d:%s", b.cfg.Host, b.cfg.Port, strings.Join(b.cfg.To, ","))
+}
+
+func (b *SMTPBackend) Deliver(ctx context.Context, event Event) error {
+	addr := fmt.Sprintf("%s:%d", b.cfg.Host, b.cfg.Port)
+
+	var auth smtp.Auth
+	if b.cfg.Username != "" {
+		auth = smtp.PlainAuth("", b.cfg.Username, b.cfg.Password, b.cfg.Host)
+	}
+
+	body := fmt.Sprintf("Subject: [%s] profile %s changed state\r\n\r\ndevice: %s\nprofile: %s\n%s -> %s\nat: %s\n",
+		event.DeviceID, event.Profile, event.DeviceID, event.Profile, event.Previous, event.Current, event.Timestamp)
+
+	if err := sendMailContext(ctx, addr, auth, b.cfg.From, b.cfg.To, []byte(body)); err != nil {
+		return fmt.Errorf("cannot send notification email: %w", err)
+	}
+
+	return nil
+}
+
+// sendMailContext is smtp.SendMail with ctx support bolted on: the standard
+// library dials and speaks SMTP with no way to bound or cancel either step,
+// which left redeliverAttemptTimeout (see Manager.replayOutboxOnce) unable to bound
+// an SMTP delivery. The connection is dialed through ctx and torn down the
+// moment ctx is done, unblocking whichever SMTP command is in flight.
+func sendMailContext(ctx context.Context, addr string, auth smtp.Auth, from string, to []string, body []byte) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("cannot connect to smtp relay: %w", err)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("cannot start smtp session: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("smtp STARTTLS failed: %w", err)
+		}
+	}
+
+	if auth != nil {
+		if ok, _ := client.Extension("AUTH"); ok {
+			if err := client.Auth(auth); err != nil {
+				return fmt.Errorf("smtp auth failed: %w", err)
+			}
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return fmt.Errorf("smtp MAIL FROM failed: %w", err)
+	}
+	for _, recipient := range to {
+		if err := client.Rcpt(recipient); err != nil {
+			return fmt.Errorf("smtp RCPT TO failed: %w", err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp DATA failed: %w", err)
+	}
+	if _, err := w.Write(body); err != nil {
+		return fmt.Errorf("cannot write email body: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("cannot finish email body: %w", err)
+	}
+
+	return client.Quit()
+}
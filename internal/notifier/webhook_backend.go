@@ -0,0 +1,70 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// WebhookConfig configures a WebhookBackend.
+type WebhookConfig struct {
+	URL    string
+	Secret string // used to HMAC-sign the payload, if set
+}
+
+// WebhookBackend POSTs a JSON encoded Event to a configured URL, signing the
+// body with HMAC-SHA256 when a secret is configured.
+type WebhookBackend struct {
+	cfg    WebhookConfig
+	client *http.Client
+}
+
+// NewWebhookBackend creates a Backend delivering events as signed webhooks.
+func NewWebhookBackend(cfg WebhookConfig) *WebhookBackend {
+	return &WebhookBackend{cfg: cfg, client: &http.Client{}}
+}
+
+// Name identifies this backend instance by its destination URL, so two
+// webhook routes pointing at different URLs are never confused with each
+// other when the outbox replays a failed delivery (see Manager.redeliver).
+func (b *WebhookBackend) Name() string { return fmt.Sprintf("webhook:%s", b.cfg.URL) }
+
+func (b *WebhookBackend) Deliver(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("cannot encode notification payload: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, b.cfg.URL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("cannot create webhook request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	if b.cfg.Secret != "" {
+		request.Header.Set("X-Signature-256", signPayload(b.cfg.Secret, payload))
+	}
+
+	resp, err := b.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("cannot deliver webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("webhook rejected notification. code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func signPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,156 @@
+// Package scheduler turns the desired-state updates emitted by the
+// configuration manager into executor.Start/Stop calls, reconciling running
+// workloads against the latest requested set.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/tupyy/device-worker-ng/internal/entities"
+	"github.com/tupyy/device-worker-ng/internal/executor"
+	"github.com/tupyy/device-worker-ng/internal/metrics"
+	"github.com/tupyy/device-worker-ng/internal/resources"
+	"go.uber.org/zap"
+)
+
+// WorkloadRequest is a single desired-state entry for a workload, as emitted
+// by the configuration manager whenever the operator pushes a new workload
+// list.
+type WorkloadRequest struct {
+	ID      string
+	Command string
+	Args    []string
+}
+
+// Scheduler reconciles WorkloadRequests against the executor, starting new
+// workloads, stopping ones no longer requested, and remembering each
+// workload's command so it can be restarted on demand.
+type Scheduler struct {
+	executor        *executor.Executor
+	resourceManager *resources.Manager
+	metrics         *metrics.Registry
+
+	mu       sync.Mutex
+	requests map[string]WorkloadRequest
+
+	// ctx is the run context passed to Start, kept so RestartWorkload can
+	// start the workload tied to the same lifetime as every other
+	// executor.Start call instead of one that outlives process shutdown.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// New creates a Scheduler. registry may be nil, in which case no metrics are
+// recorded.
+func New(executor *executor.Executor, resourceManager *resources.Manager, registry *metrics.Registry) *Scheduler {
+	return &Scheduler{
+		executor:        executor,
+		resourceManager: resourceManager,
+		metrics:         registry,
+		requests:        make(map[string]WorkloadRequest),
+	}
+}
+
+// Start begins reconciling incoming workload requests (from requests) and, if
+// non-nil, profile state transitions (from profiles) in the background.
+func (s *Scheduler) Start(ctx context.Context, requests <-chan []WorkloadRequest, profiles <-chan map[string]entities.ProfileState) {
+	ctx, cancel := context.WithCancel(ctx)
+	s.ctx = ctx
+	s.cancel = cancel
+
+	go s.run(ctx, requests, profiles)
+}
+
+func (s *Scheduler) run(ctx context.Context, requests <-chan []WorkloadRequest, profiles <-chan map[string]entities.ProfileState) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case batch, ok := <-requests:
+			if !ok {
+				return
+			}
+			// updates still buffered in requests are the ones waiting to be
+			// scheduled behind this batch.
+			s.setQueueDepth(int64(len(requests)))
+			s.reconcile(ctx, batch)
+		case states, ok := <-profiles:
+			if !ok {
+				profiles = nil
+				continue
+			}
+			zap.S().Debugw("profile state update", "states", states)
+		}
+	}
+}
+
+// reconcile starts every requested workload not yet running and stops every
+// running workload no longer present in the requested batch.
+func (s *Scheduler) reconcile(ctx context.Context, batch []WorkloadRequest) {
+	wanted := make(map[string]WorkloadRequest, len(batch))
+	for _, req := range batch {
+		wanted[req.ID] = req
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, req := range wanted {
+		if _, ok := s.requests[id]; ok {
+			continue
+		}
+		if err := s.executor.Start(ctx, req.ID, req.Command, req.Args...); err != nil {
+			zap.S().Errorw("cannot start workload", "workload", req.ID, "error", err)
+			continue
+		}
+		s.requests[id] = req
+	}
+
+	for id := range s.requests {
+		if _, ok := wanted[id]; ok {
+			continue
+		}
+		if err := s.executor.Stop(id); err != nil {
+			zap.S().Errorw("cannot stop workload", "workload", id, "error", err)
+		}
+		delete(s.requests, id)
+	}
+}
+
+// Workloads returns the executor's current workload snapshot, so the admin
+// API can report it without depending on the executor package directly.
+func (s *Scheduler) Workloads() []executor.WorkloadStatus {
+	return s.executor.Workloads()
+}
+
+// RestartWorkload stops and restarts the workload identified by id, using
+// the command it was last requested with.
+func (s *Scheduler) RestartWorkload(id string) error {
+	s.mu.Lock()
+	req, ok := s.requests[id]
+	ctx := s.ctx
+	s.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("workload %s is not known to the scheduler", id)
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := s.executor.Stop(id); err != nil {
+		return err
+	}
+
+	return s.executor.Start(ctx, req.ID, req.Command, req.Args...)
+}
+
+func (s *Scheduler) setQueueDepth(depth int64) {
+	if s.metrics == nil {
+		return
+	}
+	s.metrics.SetSchedulerQueueDepth(int(depth))
+}
@@ -0,0 +1,286 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/tupyy/device-worker-ng/internal/certificate"
+)
+
+const selfSignedValidity = 365 * 24 * time.Hour
+
+// buildClient builds a tls.Config for dialing out to a server. It requires
+// either a CA root or SkipCA, and optionally loads a client certificate/key
+// for mTLS.
+func (p *Profile) buildClient() error {
+	cfg := &tls.Config{InsecureSkipVerify: p.cfg.SkipCA}
+
+	if !p.cfg.SkipCA {
+		if p.cfg.CARootFile == "" {
+			return fmt.Errorf("client profile requires ca-root or skip-ca")
+		}
+
+		pool, err := loadCARoot(p.cfg.CARootFile)
+		if err != nil {
+			return err
+		}
+		cfg.RootCAs = pool
+	}
+
+	if p.cfg.CertFile != "" && p.cfg.KeyFile != "" {
+		certManager, err := p.loadCertManager()
+		if err != nil {
+			return err
+		}
+
+		p.mu.Lock()
+		p.certManager = certManager
+		p.mu.Unlock()
+
+		roots, leaf, key := certManager.GetCertificates()
+		if !p.cfg.SkipCA && cfg.RootCAs == nil {
+			cfg.RootCAs = roots
+		}
+		cfg.Certificates = []tls.Certificate{certFromParts(leaf, key)}
+	}
+
+	p.setTLSConfig(cfg)
+	return nil
+}
+
+// buildServer builds a tls.Config for a local server (e.g. the admin API). It
+// requires either a certificate/key pair or AutoCerts.
+func (p *Profile) buildServer() error {
+	cfg := &tls.Config{}
+
+	if p.cfg.AutoCerts {
+		cert, err := selfSignedCertificate(p.cfg.Name)
+		if err != nil {
+			return err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+		p.setTLSConfig(cfg)
+		return nil
+	}
+
+	if p.cfg.CertFile == "" || p.cfg.KeyFile == "" {
+		return fmt.Errorf("server profile requires cert/key or auto-certs")
+	}
+
+	certManager, err := p.loadCertManager()
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.certManager = certManager
+	p.mu.Unlock()
+
+	_, leaf, key := certManager.GetCertificates()
+	cfg.Certificates = []tls.Certificate{certFromParts(leaf, key)}
+
+	p.setTLSConfig(cfg)
+	return nil
+}
+
+// buildPeer builds a tls.Config for device-to-device sync, where both sides
+// present and validate a certificate. It requires cert/key/ca or AutoCerts.
+func (p *Profile) buildPeer() error {
+	cfg := &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert}
+
+	if p.cfg.AutoCerts {
+		cert, err := selfSignedCertificate(p.cfg.Name)
+		if err != nil {
+			return err
+		}
+		pool := x509.NewCertPool()
+		pool.AddCert(cert.Leaf)
+		cfg.Certificates = []tls.Certificate{cert}
+		cfg.RootCAs = pool
+		cfg.ClientCAs = pool
+		p.setTLSConfig(cfg)
+		return nil
+	}
+
+	if p.cfg.CertFile == "" || p.cfg.KeyFile == "" || p.cfg.CARootFile == "" {
+		return fmt.Errorf("peer profile requires cert/key/ca or auto-certs")
+	}
+
+	pool, err := loadCARoot(p.cfg.CARootFile)
+	if err != nil {
+		return err
+	}
+
+	certManager, err := p.loadCertManager()
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.certManager = certManager
+	p.mu.Unlock()
+
+	_, leaf, key := certManager.GetCertificates()
+	cfg.Certificates = []tls.Certificate{certFromParts(leaf, key)}
+	cfg.RootCAs = pool
+	cfg.ClientCAs = pool
+
+	p.setTLSConfig(cfg)
+	return nil
+}
+
+// buildCluster builds the mTLS config used by the httpClient to talk to the
+// flotta operator. It always requires a ca-root and a cert/key pair.
+func (p *Profile) buildCluster() error {
+	if p.cfg.CARootFile == "" || p.cfg.CertFile == "" || p.cfg.KeyFile == "" {
+		return fmt.Errorf("cluster profile requires ca-root, cert and key")
+	}
+
+	pool, err := loadCARoot(p.cfg.CARootFile)
+	if err != nil {
+		return err
+	}
+
+	certManager, err := p.loadCertManager()
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.certManager = certManager
+	p.mu.Unlock()
+
+	// the client certificate is read from certManager at handshake time, so
+	// a renewal applied through certManager.Replace is presented as soon as
+	// the signature bump makes the http client rebuild its transport.
+	cfg := &tls.Config{
+		RootCAs: pool,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			_, leaf, key := certManager.GetCertificates()
+			cert := certFromParts(leaf, key)
+			return &cert, nil
+		},
+	}
+
+	p.setTLSConfig(cfg)
+	return nil
+}
+
+func (p *Profile) setTLSConfig(cfg *tls.Config) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.tlsConfig = cfg
+}
+
+// loadCertManager reads the profile's certificate/key pair from disk. If the
+// profile already has a certManager from a previous build, it updates that
+// same Manager in place via Replace instead of constructing a new one, so
+// anything holding onto the original pointer (e.g. certificate.Manager.
+// StartAutoRenewal's background goroutine) keeps observing the reloaded
+// certificate/key across a Watch-triggered rebuild.
+func (p *Profile) loadCertManager() (*certificate.Manager, error) {
+	cert, err := os.ReadFile(p.cfg.CertFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read certificate: %w", err)
+	}
+
+	key, err := os.ReadFile(p.cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read private key: %w", err)
+	}
+
+	p.mu.RLock()
+	existing := p.certManager
+	p.mu.RUnlock()
+
+	if existing != nil {
+		if err := existing.Replace(cert, key); err != nil {
+			return nil, fmt.Errorf("cannot reload certificate: %w", err)
+		}
+		return existing, nil
+	}
+
+	var roots [][]byte
+	if p.cfg.CARootFile != "" {
+		caRoot, err := os.ReadFile(p.cfg.CARootFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read ca root: %w", err)
+		}
+		roots = [][]byte{caRoot}
+	}
+
+	return certificate.New(roots, cert, key)
+}
+
+func loadCARoot(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read ca root: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("cannot parse ca root")
+	}
+
+	return pool, nil
+}
+
+func certFromParts(leaf *x509.Certificate, key interface{}) tls.Certificate {
+	return tls.Certificate{
+		Certificate: [][]byte{leaf.Raw},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}
+}
+
+// selfSignedCertificate mints an in-memory self-signed certificate, used by
+// profiles configured with AutoCerts so local endpoints (admin API, peer
+// sync) can run without requiring an operator-issued certificate.
+func selfSignedCertificate(commonName string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("cannot generate key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("cannot generate serial number: %w", err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("cannot create self-signed certificate: %w", err)
+	}
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("cannot parse self-signed certificate: %w", err)
+	}
+
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}, nil
+}
@@ -0,0 +1,218 @@
+// Package tlsconfig models named TLS profiles for the different roles a
+// device-worker-ng process can take: talking to the flotta operator as a
+// client, serving a local admin endpoint, or peering with another device.
+// Each profile owns its certificates and knows how to build a ready to use
+// *tls.Config for its role, so callers never need to special case the mode.
+package tlsconfig
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tupyy/device-worker-ng/internal/certificate"
+	"go.uber.org/zap"
+)
+
+// Mode is the role a profile is used for.
+type Mode string
+
+const (
+	// ModeClient is used when dialing out to a server that presents a
+	// certificate we need to validate (e.g. against a custom CA).
+	ModeClient Mode = "client"
+
+	// ModeServer is used by a local endpoint (e.g. the admin API) which needs
+	// to present a certificate to its callers.
+	ModeServer Mode = "server"
+
+	// ModePeer is used for device-to-device communication, where both sides
+	// authenticate each other.
+	ModePeer Mode = "peer"
+
+	// ModeCluster is used by the httpClient to talk to the flotta operator
+	// and always requires mTLS.
+	ModeCluster Mode = "cluster"
+)
+
+const defaultWatchInterval = 30 * time.Second
+
+// ProfileConfig is the YAML-loadable shape of a single named TLS profile, as
+// read from configuration.InitConfiguration.
+type ProfileConfig struct {
+	Name string `yaml:"name"`
+	Mode Mode   `yaml:"mode"`
+
+	CARootFile string `yaml:"ca-root"`
+	CertFile   string `yaml:"cert"`
+	KeyFile    string `yaml:"key"`
+
+	// SkipCA disables server certificate validation. Only meaningful for
+	// ModeClient.
+	SkipCA bool `yaml:"skip-ca"`
+
+	// AutoCerts mints an in-memory self-signed certificate instead of reading
+	// CertFile/KeyFile from disk. Only meaningful for ModeServer and ModePeer.
+	AutoCerts bool `yaml:"auto-certs"`
+}
+
+// Profile is a live, hot-reloadable TLS configuration for one role.
+type Profile struct {
+	mu sync.RWMutex
+
+	cfg ProfileConfig
+
+	// certManager is non-nil whenever the profile's certificate/key pair is
+	// backed by files on disk, so it can be reloaded and its signature reused
+	// by callers that need to detect rotation (e.g. httpClient).
+	certManager *certificate.Manager
+
+	tlsConfig *tls.Config
+}
+
+// New builds a Profile for cfg, reading certificates from disk or minting a
+// self-signed one depending on the mode.
+func New(cfg ProfileConfig) (*Profile, error) {
+	p := &Profile{cfg: cfg}
+
+	var err error
+	switch cfg.Mode {
+	case ModeClient:
+		err = p.buildClient()
+	case ModeServer:
+		err = p.buildServer()
+	case ModePeer:
+		err = p.buildPeer()
+	case ModeCluster:
+		err = p.buildCluster()
+	default:
+		return nil, fmt.Errorf("unknown tls profile mode %q", cfg.Mode)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot build tls profile %q: %w", cfg.Name, err)
+	}
+
+	return p, nil
+}
+
+// TLSConfig returns a copy of the current *tls.Config for this profile, safe
+// to hand to an http.Transport or http.Server. It reflects the latest reload.
+func (p *Profile) TLSConfig() *tls.Config {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.tlsConfig.Clone()
+}
+
+// Signature identifies the current certificate/key pair backing this profile.
+// It is zero length for profiles which are not backed by a certificate.Manager
+// (auto-certs, skip-ca).
+func (p *Profile) Signature() []byte {
+	p.mu.RLock()
+	certManager := p.certManager
+	p.mu.RUnlock()
+
+	if certManager == nil {
+		return nil
+	}
+
+	return certManager.Signature()
+}
+
+// CertManager returns the certificate.Manager backing this profile, or nil if
+// the profile does not read its certificates from disk (auto-certs,
+// skip-ca). Callers that need to drive auto-renewal (see certificate.Manager.
+// StartAutoRenewal) use this to get at the underlying manager.
+func (p *Profile) CertManager() *certificate.Manager {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.certManager
+}
+
+// Watch polls the backing certificate files every interval (defaultWatchInterval
+// if zero) and rebuilds the TLS config when they change, so profiles loaded
+// from disk pick up an externally rotated certificate/key without a restart.
+func (p *Profile) Watch(ctx context.Context, interval time.Duration) {
+	if p.CertManager() == nil {
+		return
+	}
+
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastFileSignature, err := p.fileSignature()
+	if err != nil {
+		zap.S().Errorw("cannot read tls profile files", "profile", p.cfg.Name, "error", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fileSignature, err := p.fileSignature()
+			if err != nil {
+				zap.S().Errorw("cannot read tls profile files", "profile", p.cfg.Name, "error", err)
+				continue
+			}
+
+			if bytes.Equal(fileSignature, lastFileSignature) {
+				continue
+			}
+
+			lastFileSignature = fileSignature
+			if err := p.rebuild(); err != nil {
+				zap.S().Errorw("cannot reload tls profile", "profile", p.cfg.Name, "error", err)
+				continue
+			}
+
+			zap.S().Infow("tls profile reloaded", "profile", p.cfg.Name)
+		}
+	}
+}
+
+// fileSignature hashes the profile's certificate/key files as currently on
+// disk, independent of what certManager last loaded. Watch uses this to
+// detect an external rewrite of those files before deciding to rebuild,
+// since the in-memory Signature only changes as a result of rebuild itself.
+func (p *Profile) fileSignature() ([]byte, error) {
+	cert, err := os.ReadFile(p.cfg.CertFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read certificate: %w", err)
+	}
+
+	key, err := os.ReadFile(p.cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read private key: %w", err)
+	}
+
+	h := sha256.New()
+	h.Write(cert)
+	h.Write(key)
+	return h.Sum(nil), nil
+}
+
+func (p *Profile) rebuild() error {
+	switch p.cfg.Mode {
+	case ModeClient:
+		return p.buildClient()
+	case ModeServer:
+		return p.buildServer()
+	case ModePeer:
+		return p.buildPeer()
+	case ModeCluster:
+		return p.buildCluster()
+	default:
+		return fmt.Errorf("unknown tls profile mode %q", p.cfg.Mode)
+	}
+}
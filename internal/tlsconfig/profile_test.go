@@ -0,0 +1,159 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCertAndKey mints a self-signed certificate/key pair and writes it
+// as "<name>-cert.pem"/"<name>-key.pem" under dir, returning their paths.
+func writeTestCertAndKey(t *testing.T, dir, name string) (certPath, keyPath string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("cannot generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("cannot create certificate: %v", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("cannot marshal key: %v", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	certPath = filepath.Join(dir, name+"-cert.pem")
+	keyPath = filepath.Join(dir, name+"-key.pem")
+
+	if err := os.WriteFile(certPath, certPEM, 0o600); err != nil {
+		t.Fatalf("cannot write cert: %v", err)
+	}
+	if err := os.WriteFile(keyPath, keyPEM, 0o600); err != nil {
+		t.Fatalf("cannot write key: %v", err)
+	}
+
+	return certPath, keyPath
+}
+
+func TestNewUnknownMode(t *testing.T) {
+	_, err := New(ProfileConfig{Name: "bad", Mode: "bogus"})
+	if err == nil {
+		t.Fatal("New() with an unknown mode should error")
+	}
+}
+
+func TestNewClientRequiresCARootOrSkipCA(t *testing.T) {
+	_, err := New(ProfileConfig{Name: "client", Mode: ModeClient})
+	if err == nil {
+		t.Fatal("New() for ModeClient without ca-root or skip-ca should error")
+	}
+}
+
+func TestNewClientWithSkipCA(t *testing.T) {
+	p, err := New(ProfileConfig{Name: "client", Mode: ModeClient, SkipCA: true})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	cfg := p.TLSConfig()
+	if !cfg.InsecureSkipVerify {
+		t.Error("TLSConfig().InsecureSkipVerify = false, want true when SkipCA is set")
+	}
+	if p.Signature() != nil {
+		t.Error("Signature() should be nil for a profile not backed by a certificate.Manager")
+	}
+}
+
+func TestNewServerRequiresCertOrAutoCerts(t *testing.T) {
+	_, err := New(ProfileConfig{Name: "server", Mode: ModeServer})
+	if err == nil {
+		t.Fatal("New() for ModeServer without cert/key or auto-certs should error")
+	}
+}
+
+func TestNewServerWithAutoCerts(t *testing.T) {
+	p, err := New(ProfileConfig{Name: "server", Mode: ModeServer, AutoCerts: true})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	cfg := p.TLSConfig()
+	if len(cfg.Certificates) != 1 {
+		t.Fatalf("TLSConfig().Certificates has %d entries, want 1", len(cfg.Certificates))
+	}
+	if p.Signature() != nil {
+		t.Error("Signature() should be nil for an auto-certs profile")
+	}
+}
+
+func TestNewPeerRequiresCertKeyCAOrAutoCerts(t *testing.T) {
+	_, err := New(ProfileConfig{Name: "peer", Mode: ModePeer})
+	if err == nil {
+		t.Fatal("New() for ModePeer without cert/key/ca or auto-certs should error")
+	}
+}
+
+func TestNewClusterRequiresCertAndCA(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := writeTestCertAndKey(t, dir, "leaf")
+
+	_, err := New(ProfileConfig{Name: "cluster", Mode: ModeCluster, CertFile: certPath, KeyFile: keyPath})
+	if err == nil {
+		t.Fatal("New() for ModeCluster without ca-root should error")
+	}
+}
+
+func TestNewClusterLoadsCertManager(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := writeTestCertAndKey(t, dir, "leaf")
+	caPath, _ := writeTestCertAndKey(t, dir, "ca")
+	caPEM, err := os.ReadFile(caPath)
+	if err != nil {
+		t.Fatalf("cannot read generated ca: %v", err)
+	}
+	caRootPath := filepath.Join(dir, "ca-root.pem")
+	if err := os.WriteFile(caRootPath, caPEM, 0o600); err != nil {
+		t.Fatalf("cannot write ca root: %v", err)
+	}
+
+	p, err := New(ProfileConfig{
+		Name:       "cluster",
+		Mode:       ModeCluster,
+		CARootFile: caRootPath,
+		CertFile:   certPath,
+		KeyFile:    keyPath,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if p.CertManager() == nil {
+		t.Error("CertManager() = nil, want a manager backed by the loaded cert/key")
+	}
+	if len(p.Signature()) == 0 {
+		t.Error("Signature() should be non-empty once a certManager is loaded")
+	}
+}
+